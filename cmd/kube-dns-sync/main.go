@@ -19,23 +19,29 @@ import (
 
 	"github.com/wikiwi/kube-dns-sync/pkg/controller"
 	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/types"
 )
 
 func main() {
 	parser := flags.NewParser(&opts, flags.Default)
 	parser.FindOptionByLongName("dns-provider").Choices = dnsprovider.RegisteredDnsProviders()
 	parser.Name = "kube-dns-sync"
-	_, err := parser.Parse()
+	parser.SubcommandsOptional = true
+	_, err := parser.AddCommand("plan", "Print the changes of a sync without applying them",
+		"Computes the changes a sync would apply against the live zones and prints them.", &planCmd)
+	if err != nil {
+		panic(err)
+	}
+	_, err = parser.Parse()
 	if err != nil {
 		if e2, ok := err.(*flags.Error); ok && e2.Type == flags.ErrHelp {
 			os.Exit(0)
 		}
 		os.Exit(1)
 	}
-
-	if opts.ApexAddressType == "" && len(opts.AddressTypes) == 0 {
-		fmt.Println("neither --address-types nor --apex-address-type is specified")
-		os.Exit(1)
+	if parser.Active != nil {
+		// A subcommand was executed.
+		os.Exit(0)
 	}
 
 	dump, err := yaml.Marshal(opts)
@@ -43,21 +49,108 @@ func main() {
 		panic(err)
 	}
 	fmt.Printf("Starting with following configuration\n%s", string(dump))
-	dnsProvider, err := dnsprovider.InitDnsProvider(opts.DNSProvider, string(opts.DNSProviderConfig))
+	c, err := newController()
 	if err != nil {
-		panic(err)
+		fmt.Println(err)
+		os.Exit(1)
 	}
-	c, err := controller.New(&controller.Options{
-		DNSProvider:     dnsProvider,
-		TTL:             opts.TTL,
-		ZoneName:        opts.ZoneName,
-		SyncInterval:    opts.SyncInterval,
-		AddressTypes:    opts.AddressTypes,
-		ApexAddressType: api.NodeAddressType(opts.ApexAddressType),
-		Selector:        opts.SelectorType.Selector,
-	})
-	if err != nil {
-		panic(err)
+	if opts.ListenAddress != "" {
+		serveHTTP(opts.ListenAddress, c)
 	}
 	panic(c.Run())
 }
+
+// newController validates the options and creates the Controller.
+func newController() (*controller.Controller, error) {
+	if opts.ZoneName == "" && opts.ZonesConfig == "" {
+		return nil, fmt.Errorf("neither --zone-name nor --zones-config is specified")
+	}
+	if opts.ZoneName != "" && opts.ApexAddressType == "" && len(opts.AddressTypes) == 0 {
+		return nil, fmt.Errorf("neither --address-types nor --apex-address-type is specified")
+	}
+	var zones []controller.ZoneOptions
+	if opts.ZonesConfig != "" {
+		var err error
+		zones, err = loadZonesConfig(string(opts.ZonesConfig))
+		if err != nil {
+			return nil, fmt.Errorf("invalid --zones-config: %v", err)
+		}
+	}
+
+	var leaderElection *controller.LeaderElectionOptions
+	if opts.LeaderElect {
+		leaderElection = &controller.LeaderElectionOptions{
+			Namespace:     opts.LeaderElectNamespace,
+			Name:          opts.LeaderElectName,
+			Identity:      opts.LeaderElectIdentity,
+			LeaseDuration: opts.LeaderElectLeaseDuration,
+			RenewDeadline: opts.LeaderElectRenewDeadline,
+			RetryPeriod:   opts.LeaderElectRetryPeriod,
+		}
+	}
+
+	eligibility := controller.NodeEligibility{
+		RequireInclude:       opts.RequireIncludeAnnotation,
+		ExcludeUnschedulable: opts.ExcludeUnschedulable,
+	}
+	for _, x := range opts.ExcludeTaintEffects {
+		eligibility.ExcludeTaintEffects = append(eligibility.ExcludeTaintEffects, api.TaintEffect(x))
+	}
+	for _, x := range opts.ExcludeConditions {
+		eligibility.ExcludeConditions = append(eligibility.ExcludeConditions, api.NodeConditionType(x))
+	}
+
+	var eventObject *api.ObjectReference
+	if opts.PodName != "" {
+		eventObject = &api.ObjectReference{
+			Kind:      "Pod",
+			Namespace: opts.PodNamespace,
+			Name:      opts.PodName,
+			UID:       types.UID(opts.PodUID),
+		}
+	}
+
+	dnsProvider, err := initDNSProvider(opts.DNSProvider, string(opts.DNSProviderConfig))
+	if err != nil {
+		return nil, err
+	}
+	return controller.New(&controller.Options{
+		DNSProvider:           dnsProvider,
+		DNSProviderName:       opts.DNSProvider,
+		TTL:                   opts.TTL,
+		ZoneName:              opts.ZoneName,
+		Zones:                 zones,
+		CreateZone:            opts.CreateZone,
+		DryRun:                opts.DryRun,
+		SyncInterval:          opts.SyncInterval,
+		DriftCheckInterval:    opts.DriftCheckInterval,
+		Debounce:              opts.Debounce,
+		MinSyncInterval:       opts.MinSyncInterval,
+		RetryBackoff:          opts.RetryBackoff,
+		MaxBackoff:            opts.MaxBackoff,
+		FailureThreshold:      opts.FailureThreshold,
+		LivenessFactor:        opts.LivenessFactor,
+		AddressTypes:          opts.AddressTypes,
+		ApexAddressType:       api.NodeAddressType(opts.ApexAddressType),
+		Selector:              opts.SelectorType.Selector,
+		NodeEligibility:       eligibility,
+		KeepStaleRecords:      opts.KeepStaleRecords,
+		OwnerID:               opts.OwnerID,
+		AdoptExisting:         opts.AdoptExisting,
+		NodeRecords:           opts.NodeRecords,
+		GroupByLabel:          opts.GroupByLabel,
+		TopologyRecords:       opts.TopologyRecords,
+		LatencyRouting:        opts.LatencyRouting,
+		WeightedRecords:       opts.WeightedRecords,
+		HealthCheckPort:       opts.HealthCheckPort,
+		MaxAddressesPerRecord: opts.MaxAddressesPerRecord,
+		RecordNameTemplate:    opts.RecordNameTemplate,
+		IPFamily:              controller.IPFamily(opts.IPFamily),
+		SyncServices:          opts.SyncServices,
+		SyncIngresses:         opts.SyncIngresses,
+		ServiceAddressType:    api.NodeAddressType(opts.ServiceAddressType),
+		LeaderElection:        leaderElection,
+		Events:                opts.Events,
+		EventObject:           eventObject,
+	})
+}
@@ -19,16 +19,57 @@ import (
 )
 
 var opts struct {
-	DNSProvider       string         `long:"dns-provider" env:"KDS_PROVIDER" description:"DNS provider" required:"yes"`
-	DNSProviderConfig flags.Filename `long:"dns-provider-config" env:"KDS_PROVIDER_CONFIG" description:"Path to config file for configuring DNS provider"`
-	ZoneName          string         `long:"zone-name" env:"KDS_ZONE_NAME" description:"Zone name, like example.com" required:"yes"`
-	SyncInterval      time.Duration  `long:"sync-interval" default:"60s" env:"KDS_INTERVAL" description:"Interval for syncing with the DNS Provider"`
-	TTL               int64          `long:"ttl" default:"60" env:"KDS_TTL" description:"TTL value of DNS Records"`
-	AddressTypes      addressTypes   `long:"address-types" env:"KDS_ADDRESS_TYPES" description:"Comma list of address types to sync [externalip|internalip|legacyhostip]"`
-	ApexAddressType   addressType    `long:"apex-address-type" env:"KDS_APEX_ADDRESS_TYPE" description:"Address type that is synced to the Apex Zone" choice:"externalip" choice:"internalip" choice:"legacyhostip"`
-	SelectorType      selectorType   `long:"selector" env:"KDS_SELECTOR" description:"Node selector e.g. 'cloud.google.com/gke-nodepool=default-pool'"`
-	Verbose           func()         `yaml:"-" long:"verbose"  description:"Turn on verbose logging"`
-	Version           func()         `yaml:"-" long:"version" short:"v" description:"Show version number"`
+	DNSProvider              string         `long:"dns-provider" env:"KDS_PROVIDER" description:"DNS provider" required:"yes"`
+	DNSProviderConfig        flags.Filename `long:"dns-provider-config" env:"KDS_PROVIDER_CONFIG" description:"Path to config file for configuring DNS provider"`
+	ZoneName                 string         `long:"zone-name" env:"KDS_ZONE_NAME" description:"Zone name, like example.com"`
+	ZonesConfig              flags.Filename `long:"zones-config" env:"KDS_ZONES_CONFIG" description:"Path to YAML file configuring additional zones"`
+	CreateZone               bool           `long:"create-zone" env:"KDS_CREATE_ZONE" description:"Create missing zones instead of waiting until they are created"`
+	DryRun                   bool           `long:"dry-run" env:"KDS_DRY_RUN" description:"Log the changes of each sync without applying them"`
+	SyncInterval             time.Duration  `long:"sync-interval" default:"60s" env:"KDS_INTERVAL" description:"Interval for syncing with the DNS Provider"`
+	DriftCheckInterval       time.Duration  `long:"drift-check-interval" default:"10m" env:"KDS_DRIFT_CHECK_INTERVAL" description:"Interval for listing all records from the DNS Provider to detect changes made by others, 0 to list them in every sync"`
+	Debounce                 time.Duration  `long:"debounce" default:"2s" env:"KDS_DEBOUNCE" description:"Time a sync requested by a change waits for further changes"`
+	MinSyncInterval          time.Duration  `long:"min-sync-interval" default:"10s" env:"KDS_MIN_SYNC_INTERVAL" description:"Minimum time between the start of two syncs"`
+	RetryBackoff             time.Duration  `long:"retry-backoff" default:"1s" env:"KDS_RETRY_BACKOFF" description:"Delay before retrying a failed sync, doubles with each consecutive failure"`
+	MaxBackoff               time.Duration  `long:"max-backoff" default:"5m" env:"KDS_MAX_BACKOFF" description:"Maximum delay between retries of failed syncs"`
+	FailureThreshold         int            `long:"failure-threshold" default:"10" env:"KDS_FAILURE_THRESHOLD" description:"Number of consecutive failed syncs after which the circuit breaker opens and syncs only every --max-backoff, negative to disable"`
+	LivenessFactor           int            `long:"liveness-factor" default:"3" env:"KDS_LIVENESS_FACTOR" description:"Number of sync intervals without a completed sync after which /healthz fails"`
+	TTL                      int64          `long:"ttl" default:"60" env:"KDS_TTL" description:"TTL value of DNS Records"`
+	AddressTypes             addressTypes   `long:"address-types" env:"KDS_ADDRESS_TYPES" description:"Comma list of address types to sync [externalip|internalip|legacyhostip]"`
+	ApexAddressType          addressType    `long:"apex-address-type" env:"KDS_APEX_ADDRESS_TYPE" description:"Address type that is synced to the Apex Zone" choice:"externalip" choice:"internalip" choice:"legacyhostip"`
+	SelectorType             selectorType   `long:"selector" env:"KDS_SELECTOR" description:"Node selector e.g. 'cloud.google.com/gke-nodepool=default-pool'"`
+	RequireIncludeAnnotation bool           `long:"require-include-annotation" env:"KDS_REQUIRE_INCLUDE_ANNOTATION" description:"Only sync nodes annotated with kube-dns-sync/include=true"`
+	ExcludeUnschedulable     bool           `long:"exclude-unschedulable" env:"KDS_EXCLUDE_UNSCHEDULABLE" description:"Exclude cordoned and draining nodes"`
+	ExcludeTaintEffects      stringList     `long:"exclude-taint-effects" env:"KDS_EXCLUDE_TAINT_EFFECTS" description:"Comma list of taint effects excluding nodes e.g. 'NoSchedule'"`
+	ExcludeConditions        stringList     `long:"exclude-conditions" env:"KDS_EXCLUDE_CONDITIONS" description:"Comma list of node conditions excluding nodes when true e.g. 'DiskPressure,NetworkUnavailable'"`
+	KeepStaleRecords         bool           `long:"keep-stale-records" env:"KDS_KEEP_STALE_RECORDS" description:"Keep managed records that are no longer desired instead of removing them"`
+	IPFamily                 string         `long:"ip-family" default:"dual" env:"KDS_IP_FAMILY" description:"Sync IPv4 addresses to A records, IPv6 addresses to AAAA records or both" choice:"ipv4" choice:"ipv6" choice:"dual"`
+	RecordNameTemplate       string         `long:"record-name-template" default:"{{.AddressType}}.{{.Zone}}" env:"KDS_RECORD_NAME_TEMPLATE" description:"Go template for record names with access to .AddressType, .NodeName, .Labels and .Zone"`
+	NodeRecords              bool           `long:"node-records" env:"KDS_NODE_RECORDS" description:"Additionally sync a record per node e.g. node1.externalip.example.com"`
+	GroupByLabel             string         `long:"group-by-label" env:"KDS_GROUP_BY_LABEL" description:"Additionally sync a record per value of the node label e.g. pool-a.externalip.example.com for 'cloud.google.com/gke-nodepool'"`
+	TopologyRecords          bool           `long:"topology-records" env:"KDS_TOPOLOGY_RECORDS" description:"Additionally sync a record per failure domain zone and region of the nodes e.g. us-east-1a.externalip.example.com"`
+	LatencyRouting           bool           `long:"latency-routing" env:"KDS_LATENCY_ROUTING" description:"Sync records per node region with a latency routing policy (aws-route53 only)"`
+	WeightedRecords          bool           `long:"weighted-records" env:"KDS_WEIGHTED_RECORDS" description:"Sync records per node with a weighted routing policy, see annotation kube-dns-sync/weight (aws-route53 only)"`
+	HealthCheckPort          int            `long:"health-check-port" env:"KDS_HEALTH_CHECK_PORT" description:"Bind weighted records to TCP health checks of the node at this port, 0 to disable (aws-route53 only)"`
+	MaxAddressesPerRecord    int            `long:"max-addresses-per-record" env:"KDS_MAX_ADDRESSES_PER_RECORD" description:"Maximum number of addresses of each record, chosen by rendezvous hashing on the node names, 0 for all"`
+	SyncServices             bool           `long:"sync-services" env:"KDS_SYNC_SERVICES" description:"Sync services annotated with kube-dns-sync/publish=true to <service>.<namespace>.<zone>"`
+	SyncIngresses            bool           `long:"sync-ingresses" env:"KDS_SYNC_INGRESSES" description:"Sync hosts of ingresses that are inside of the zone"`
+	ServiceAddressType       addressType    `long:"service-address-type" default:"externalip" env:"KDS_SERVICE_ADDRESS_TYPE" description:"Address type of the nodes that is synced for NodePort services and ingresses without load balancer" choice:"externalip" choice:"internalip" choice:"legacyhostip"`
+	ListenAddress            string         `long:"listen-address" env:"KDS_LISTEN_ADDRESS" description:"Address serving the /metrics, /healthz and /readyz endpoints like ':9090', disabled when empty"`
+	OwnerID                  string         `long:"owner-id" default:"default" env:"KDS_OWNER_ID" description:"Identifies this instance in the ownership TXT records, must be unique per zone"`
+	AdoptExisting            bool           `long:"adopt-existing" env:"KDS_ADOPT_EXISTING" description:"Take over desired records without ownership TXT record, e.g. created by older versions"`
+	LeaderElect              bool           `long:"leader-elect" env:"KDS_LEADER_ELECT" description:"Elect a leader between replicas, only the leader syncs to DNS"`
+	LeaderElectNamespace     string         `long:"leader-elect-namespace" default:"default" env:"KDS_LEADER_ELECT_NAMESPACE" description:"Namespace of the endpoints object used as leader election lock"`
+	LeaderElectName          string         `long:"leader-elect-name" default:"kube-dns-sync" env:"KDS_LEADER_ELECT_NAME" description:"Name of the endpoints object used as leader election lock"`
+	LeaderElectIdentity      string         `long:"leader-elect-identity" env:"KDS_LEADER_ELECT_IDENTITY" description:"Identity of this replica in the leader election, defaults to the hostname"`
+	LeaderElectLeaseDuration time.Duration  `long:"leader-elect-lease-duration" default:"15s" env:"KDS_LEADER_ELECT_LEASE_DURATION" description:"Time standbys wait after the last renewal of the leader before taking over"`
+	LeaderElectRenewDeadline time.Duration  `long:"leader-elect-renew-deadline" default:"10s" env:"KDS_LEADER_ELECT_RENEW_DEADLINE" description:"Time the leader retries renewing before giving up leadership"`
+	LeaderElectRetryPeriod   time.Duration  `long:"leader-elect-retry-period" default:"2s" env:"KDS_LEADER_ELECT_RETRY_PERIOD" description:"Interval of acquiring and renewing the leadership"`
+	Events                   bool           `long:"events" env:"KDS_EVENTS" description:"Emit Kubernetes events about record changes on the affected nodes"`
+	PodName                  string         `long:"pod-name" env:"KDS_POD_NAME" description:"Name of the pod of this instance, additionally receives events about all record changes and failed syncs"`
+	PodNamespace             string         `long:"pod-namespace" default:"default" env:"KDS_POD_NAMESPACE" description:"Namespace of the pod of this instance"`
+	PodUID                   string         `long:"pod-uid" env:"KDS_POD_UID" description:"UID of the pod of this instance, lets kubectl describe pod list its events"`
+	Verbose                  func()         `yaml:"-" long:"verbose"  description:"Turn on verbose logging"`
+	Version                  func()         `yaml:"-" long:"version" short:"v" description:"Show version number"`
 }
 
 func init() {
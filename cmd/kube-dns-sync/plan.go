@@ -0,0 +1,68 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/wikiwi/kube-dns-sync/pkg/controller"
+)
+
+// planCommand implements the plan subcommand.
+type planCommand struct {
+	Output string `long:"output" short:"o" default:"table" description:"Output format" choice:"table" choice:"json"`
+}
+
+var planCmd planCommand
+
+// Execute prints the changes of a sync without applying them.
+func (p *planCommand) Execute(args []string) error {
+	c, err := newController()
+	if err != nil {
+		return err
+	}
+	changes, err := c.Plan()
+	if err != nil {
+		return err
+	}
+	return printChanges(os.Stdout, changes, p.Output)
+}
+
+// printChanges writes changes to w as a table or as JSON.
+func printChanges(w io.Writer, changes []controller.Change, output string) error {
+	if output == "json" {
+		data, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(w, "No changes.")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ACTION\tZONE\tTYPE\tNAME\tTTL\tDATA")
+	for _, x := range changes {
+		ttl := fmt.Sprint(x.TTL)
+		data := strings.Join(x.Rrdatas, ",")
+		if x.Action == controller.ActionUpdate {
+			ttl = fmt.Sprintf("%d -> %d", x.OldTTL, x.TTL)
+			data = fmt.Sprintf("%s -> %s", strings.Join(x.OldRrdatas, ","), data)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", x.Action, x.Zone, x.Type, x.Name, ttl, data)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/kr/pretty"
+
+	"github.com/wikiwi/kube-dns-sync/pkg/controller"
+)
+
+var testChanges = []controller.Change{
+	{Action: controller.ActionAdd, Zone: "example.com.", Name: "externalip.example.com.", Type: "A", TTL: 60, Rrdatas: []string{"1.1.1.1", "2.2.2.2"}},
+	{Action: controller.ActionUpdate, Zone: "example.com.", Name: "internalip.example.com.", Type: "A", TTL: 60, Rrdatas: []string{"10.0.0.1"}, OldTTL: 300, OldRrdatas: []string{"10.0.0.2"}},
+	{Action: controller.ActionRemove, Zone: "example.com.", Name: "legacyhostip.example.com.", Type: "TXT", TTL: 60, Rrdatas: []string{`"heritage=kube-dns-sync,owner=default"`}},
+}
+
+func TestPrintChangesTable(t *testing.T) {
+	testScenarios := []struct {
+		changes []controller.Change
+		expect  string
+	}{
+		{
+			changes: testChanges,
+			expect: `ACTION  ZONE          TYPE  NAME                       TTL        DATA
+add     example.com.  A     externalip.example.com.    60         1.1.1.1,2.2.2.2
+update  example.com.  A     internalip.example.com.    300 -> 60  10.0.0.2 -> 10.0.0.1
+remove  example.com.  TXT   legacyhostip.example.com.  60         "heritage=kube-dns-sync,owner=default"
+`,
+		},
+		{changes: nil, expect: "No changes.\n"},
+	}
+	for _, x := range testScenarios {
+		var buf bytes.Buffer
+		if err := printChanges(&buf, x.changes, "table"); err != nil {
+			t.Errorf("error printing: %v", err)
+			continue
+		}
+		if buf.String() != x.expect {
+			t.Errorf("%q != %q", buf.String(), x.expect)
+		}
+	}
+}
+
+func TestPrintChangesJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printChanges(&buf, testChanges, "json"); err != nil {
+		t.Fatalf("error printing: %v", err)
+	}
+	var decoded []controller.Change
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("error decoding: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, testChanges) {
+		t.Errorf("%v", pretty.Diff(testChanges, decoded))
+	}
+}
@@ -8,7 +8,78 @@
 package main
 
 import (
+	"github.com/aws/aws-sdk-go/aws/session"
+	awsroute53 "github.com/aws/aws-sdk-go/service/route53"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	dns "google.golang.org/api/dns/v1"
+	"google.golang.org/cloud/compute/metadata"
+	"gopkg.in/gcfg.v1"
+
+	"k8s.io/kubernetes/federation/pkg/dnsprovider"
+
 	// Blank import to force loading of the Kubernetes DNS Provider Plugins.
 	_ "k8s.io/kubernetes/federation/pkg/dnsprovider/providers/aws/route53"
 	_ "k8s.io/kubernetes/federation/pkg/dnsprovider/providers/google/clouddns"
+
+	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/changeset/clouddns"
+	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/changeset/route53"
 )
+
+// initDNSProvider initializes the DNS Provider name and adds support for
+// change sets where available.
+func initDNSProvider(name, config string) (dnsprovider.Interface, error) {
+	provider, err := dnsprovider.InitDnsProvider(name, config)
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "aws-route53":
+		// The Kubernetes Route53 provider uses the default session as well.
+		return route53.Wrap(provider, awsroute53.New(session.New())), nil
+	case "google-clouddns":
+		service, project, err := newCloudDNSService(config)
+		if err != nil {
+			return nil, err
+		}
+		return clouddns.Wrap(provider, service, project), nil
+	}
+	return provider, nil
+}
+
+// cloudDNSConfig is the config file of the Kubernetes Cloud DNS Provider.
+type cloudDNSConfig struct {
+	Global struct {
+		TokenURL  string `gcfg:"token-url"`
+		TokenBody string `gcfg:"token-body"`
+		ProjectID string `gcfg:"project-id"`
+	}
+}
+
+// newCloudDNSService creates a Cloud DNS service using the default credentials
+// and the project of config, like the Kubernetes Cloud DNS provider. The project
+// defaults to the project of the instance.
+func newCloudDNSService(config string) (*dns.Service, string, error) {
+	var cfg cloudDNSConfig
+	if config != "" {
+		if err := gcfg.ReadFileInto(&cfg, config); err != nil {
+			return nil, "", err
+		}
+	}
+	project := cfg.Global.ProjectID
+	if project == "" {
+		var err error
+		if project, err = metadata.ProjectID(); err != nil {
+			return nil, "", err
+		}
+	}
+	client, err := google.DefaultClient(oauth2.NoContext, dns.NdevClouddnsReadwriteScope)
+	if err != nil {
+		return nil, "", err
+	}
+	service, err := dns.New(client)
+	if err != nil {
+		return nil, "", err
+	}
+	return service, project, nil
+}
@@ -0,0 +1,50 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// serveHTTP serves the metrics and health endpoints of c on address in the background.
+func serveHTTP(address string, c checker) {
+	go func() {
+		logrus.Infof("Listening on %s", address)
+		logrus.Fatal(http.ListenAndServe(address, newServeMux(c)))
+	}()
+}
+
+// checker reports the health of the Controller.
+type checker interface {
+	Healthy() error
+	Ready() error
+}
+
+// newServeMux returns the handler serving /metrics, /healthz and /readyz.
+func newServeMux(c checker) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", prometheus.Handler())
+	mux.HandleFunc("/healthz", checkHandler(c.Healthy))
+	mux.HandleFunc("/readyz", checkHandler(c.Ready))
+	return mux
+}
+
+// checkHandler responds with 200 when check succeeds and 503 otherwise.
+func checkHandler(check func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := check(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	}
+}
@@ -0,0 +1,50 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeChecker struct {
+	healthy error
+	ready   error
+}
+
+func (f fakeChecker) Healthy() error { return f.healthy }
+func (f fakeChecker) Ready() error   { return f.ready }
+
+func TestServeMux(t *testing.T) {
+	testScenarios := []struct {
+		checker fakeChecker
+		path    string
+		expect  int
+	}{
+		{checker: fakeChecker{}, path: "/healthz", expect: http.StatusOK},
+		{checker: fakeChecker{}, path: "/readyz", expect: http.StatusOK},
+		{checker: fakeChecker{healthy: errors.New("stuck")}, path: "/healthz", expect: http.StatusServiceUnavailable},
+		{checker: fakeChecker{healthy: errors.New("stuck")}, path: "/readyz", expect: http.StatusOK},
+		{checker: fakeChecker{ready: errors.New("failed")}, path: "/readyz", expect: http.StatusServiceUnavailable},
+		{checker: fakeChecker{ready: errors.New("failed")}, path: "/healthz", expect: http.StatusOK},
+		{checker: fakeChecker{}, path: "/metrics", expect: http.StatusOK},
+	}
+	for _, x := range testScenarios {
+		req, err := http.NewRequest("GET", x.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recorder := httptest.NewRecorder()
+		newServeMux(x.checker).ServeHTTP(recorder, req)
+		if recorder.Code != x.expect {
+			t.Errorf("%s: %d != %d", x.path, recorder.Code, x.expect)
+		}
+	}
+}
@@ -17,6 +17,18 @@ import (
 
 type addressTypes []api.NodeAddressType
 
+func (a addressTypes) MarshalYAML() (interface{}, error) {
+	return a.MarshalFlag()
+}
+
+func (a *addressTypes) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	return a.UnmarshalFlag(value)
+}
+
 func (a addressTypes) MarshalFlag() (string, error) {
 	var s string
 	for _, x := range a {
@@ -47,6 +59,18 @@ func (a *addressTypes) UnmarshalFlag(value string) error {
 
 type addressType api.NodeAddressType
 
+func (a addressType) MarshalYAML() (interface{}, error) {
+	return a.MarshalFlag()
+}
+
+func (a *addressType) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	return a.UnmarshalFlag(value)
+}
+
 func (a addressType) MarshalFlag() (string, error) {
 	return strings.ToLower(string(a)), nil
 }
@@ -91,3 +115,30 @@ func (s *selectorType) UnmarshalFlag(value string) error {
 	s.Selector = sel
 	return nil
 }
+
+type stringList []string
+
+func (s stringList) MarshalYAML() (interface{}, error) {
+	return s.MarshalFlag()
+}
+
+func (s *stringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	return s.UnmarshalFlag(value)
+}
+
+func (s stringList) MarshalFlag() (string, error) {
+	return strings.Join(s, ","), nil
+}
+
+func (s *stringList) UnmarshalFlag(value string) error {
+	for _, x := range strings.Split(value, ",") {
+		if x = strings.TrimSpace(x); x != "" {
+			*s = append(*s, x)
+		}
+	}
+	return nil
+}
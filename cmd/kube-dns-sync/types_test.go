@@ -117,3 +117,32 @@ func TestSelectorType(t *testing.T) {
 		}
 	}
 }
+
+func TestStringList(t *testing.T) {
+	testScenarios := []struct {
+		input  string
+		expect []string
+	}{
+		{input: "NoSchedule", expect: []string{"NoSchedule"}},
+		{input: "DiskPressure,NetworkUnavailable", expect: []string{"DiskPressure", "NetworkUnavailable"}},
+		{input: "", expect: nil},
+	}
+	for _, x := range testScenarios {
+		t.Log(pretty.Sprint(x))
+		var a stringList
+		if err := a.UnmarshalFlag(x.input); err != nil {
+			t.Errorf("error unmarshalling: %q", err)
+		}
+		if !reflect.DeepEqual(x.expect, []string(a)) {
+			t.Errorf("%v", pretty.Diff(x.expect, []string(a)))
+		}
+		marshalled, err := a.MarshalFlag()
+		if err != nil {
+			t.Errorf("error marshalling: %q", err)
+			continue
+		}
+		if marshalled != x.input {
+			t.Errorf("%q != %q", marshalled, x.input)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+
+	"k8s.io/kubernetes/pkg/api"
+
+	"github.com/wikiwi/kube-dns-sync/pkg/controller"
+)
+
+// zonesConfig is the format of the file passed with --zones-config.
+type zonesConfig struct {
+	Zones []zoneConfig `yaml:"zones"`
+}
+
+// zoneConfig configures a single zone, unset ttl and record-name-template
+// fall back to the values of the command line.
+type zoneConfig struct {
+	Name               string       `yaml:"name"`
+	TTL                int64        `yaml:"ttl"`
+	AddressTypes       addressTypes `yaml:"address-types"`
+	ApexAddressType    addressType  `yaml:"apex-address-type"`
+	Selector           selectorType `yaml:"selector"`
+	RecordNameTemplate string       `yaml:"record-name-template"`
+	NodeRecords        bool         `yaml:"node-records"`
+	GroupByLabel       string       `yaml:"group-by-label"`
+	TopologyRecords    bool         `yaml:"topology-records"`
+	LatencyRouting     bool         `yaml:"latency-routing"`
+	WeightedRecords    bool         `yaml:"weighted-records"`
+	SyncServices       bool         `yaml:"sync-services"`
+	SyncIngresses      bool         `yaml:"sync-ingresses"`
+}
+
+// loadZonesConfig reads the zones configured in the file at path.
+func loadZonesConfig(path string) ([]controller.ZoneOptions, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseZonesConfig(data)
+}
+
+// parseZonesConfig parses the zones configured in data.
+func parseZonesConfig(data []byte) ([]controller.ZoneOptions, error) {
+	var config zonesConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	var zones []controller.ZoneOptions
+	for _, x := range config.Zones {
+		zone := controller.ZoneOptions{
+			Name:               x.Name,
+			TTL:                x.TTL,
+			AddressTypes:       x.AddressTypes,
+			ApexAddressType:    api.NodeAddressType(x.ApexAddressType),
+			Selector:           x.Selector.Selector,
+			RecordNameTemplate: x.RecordNameTemplate,
+			NodeRecords:        x.NodeRecords,
+			GroupByLabel:       x.GroupByLabel,
+			TopologyRecords:    x.TopologyRecords,
+			LatencyRouting:     x.LatencyRouting,
+			WeightedRecords:    x.WeightedRecords,
+			SyncServices:       x.SyncServices,
+			SyncIngresses:      x.SyncIngresses,
+		}
+		if zone.TTL == 0 {
+			zone.TTL = opts.TTL
+		}
+		if zone.RecordNameTemplate == "" {
+			zone.RecordNameTemplate = opts.RecordNameTemplate
+		}
+		zones = append(zones, zone)
+	}
+	return zones, nil
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kr/pretty"
+
+	"k8s.io/kubernetes/pkg/api"
+)
+
+func TestParseZonesConfig(t *testing.T) {
+	opts.TTL = 60
+	opts.RecordNameTemplate = "{{.AddressType}}.{{.Zone}}"
+	data := []byte(`
+zones:
+- name: example.com.
+  address-types: externalip
+  weighted-records: true
+- name: internal.example.com.
+  ttl: 300
+  address-types: internalip,externalip
+  apex-address-type: internalip
+  selector: pool=private
+  record-name-template: "{{.NodeName}}.{{.Zone}}"
+  node-records: true
+  group-by-label: cloud.google.com/gke-nodepool
+  topology-records: true
+  latency-routing: true
+  sync-services: true
+  sync-ingresses: true
+`)
+	zones, err := parseZonesConfig(data)
+	if err != nil {
+		t.Fatalf("error parsing: %v", err)
+	}
+	if len(zones) != 2 {
+		t.Fatalf("expected 2 zones, got %d", len(zones))
+	}
+
+	first := zones[0]
+	if first.Name != "example.com." || first.TTL != 60 || first.RecordNameTemplate != opts.RecordNameTemplate {
+		t.Errorf("unexpected zone %s", pretty.Sprint(first))
+	}
+	if !reflect.DeepEqual(first.AddressTypes, []api.NodeAddressType{api.NodeExternalIP}) {
+		t.Errorf("%v", pretty.Diff([]api.NodeAddressType{api.NodeExternalIP}, first.AddressTypes))
+	}
+	if first.Selector != nil {
+		t.Errorf("expected no selector, got %q", first.Selector)
+	}
+
+	second := zones[1]
+	if second.Name != "internal.example.com." || second.TTL != 300 || second.RecordNameTemplate != "{{.NodeName}}.{{.Zone}}" {
+		t.Errorf("unexpected zone %s", pretty.Sprint(second))
+	}
+	if !reflect.DeepEqual(second.AddressTypes, []api.NodeAddressType{api.NodeInternalIP, api.NodeExternalIP}) {
+		t.Errorf("%v", pretty.Diff([]api.NodeAddressType{api.NodeInternalIP, api.NodeExternalIP}, second.AddressTypes))
+	}
+	if second.ApexAddressType != api.NodeInternalIP {
+		t.Errorf("%q != %q", second.ApexAddressType, api.NodeInternalIP)
+	}
+	if second.Selector == nil || second.Selector.String() != "pool=private" {
+		t.Errorf("unexpected selector %v", second.Selector)
+	}
+	if !second.NodeRecords || second.GroupByLabel != "cloud.google.com/gke-nodepool" || !second.SyncServices || !second.SyncIngresses {
+		t.Errorf("unexpected zone %s", pretty.Sprint(second))
+	}
+	if !second.TopologyRecords || !second.LatencyRouting || first.TopologyRecords || first.LatencyRouting {
+		t.Errorf("unexpected zone %s", pretty.Sprint(second))
+	}
+	if !first.WeightedRecords || second.WeightedRecords {
+		t.Errorf("unexpected zones %s", pretty.Sprint(zones))
+	}
+}
+
+func TestParseZonesConfigInvalidAddressType(t *testing.T) {
+	data := []byte(`
+zones:
+- name: example.com.
+  address-types: invalid
+`)
+	if _, err := parseZonesConfig(data); err == nil {
+		t.Errorf("expected error for invalid address type")
+	}
+}
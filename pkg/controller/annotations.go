@@ -0,0 +1,134 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"k8s.io/kubernetes/pkg/api"
+
+	k8sutil "github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes"
+)
+
+const (
+	// annotationPrefix is the prefix of the annotations read by the Controller.
+	annotationPrefix = "kube-dns-sync/"
+
+	// AnnotationAddressOverride replaces the addresses of a Node, like
+	// "5.5.5.5" for an elastic IP. It is a comma list of addresses, which
+	// can be prefixed with an address type like "internalip=10.0.0.5".
+	// Addresses without a type replace the NodeExternalIP addresses.
+	AnnotationAddressOverride = "kube-dns-sync/address-override"
+
+	// AnnotationExtraNames publishes the addresses of a Node to additional names,
+	// like "ci.example.com.". It is a comma list of names inside of the zone, which
+	// can be prefixed with an address type like "internalip=ci.internal.example.com.".
+	// Names without a type get the NodeExternalIP addresses.
+	AnnotationExtraNames = "kube-dns-sync/extra-names"
+
+	// AnnotationWeight sets the weight of the weighted Records of a Node
+	// between 0 and 255, like "0" to drain it, see WeightedRecords.
+	AnnotationWeight = "kube-dns-sync/weight"
+)
+
+// parseTypedList parses a comma list of values that can be prefixed with an
+// address type and returns the values by address type.
+func parseTypedList(value string) (map[api.NodeAddressType][]string, error) {
+	result := map[api.NodeAddressType][]string{}
+	for _, x := range strings.Split(value, ",") {
+		x = strings.TrimSpace(x)
+		if x == "" {
+			continue
+		}
+		addressType := api.NodeExternalIP
+		if i := strings.Index(x, "="); i >= 0 {
+			addressType = k8sutil.StringToAddressType(x[:i])
+			if addressType == "" {
+				return nil, fmt.Errorf("invalid address type %q", x[:i])
+			}
+			x = x[i+1:]
+		}
+		result[addressType] = append(result[addressType], x)
+	}
+	return result, nil
+}
+
+// addressOverrides returns the addresses of AnnotationAddressOverride by address type.
+func addressOverrides(node *api.Node) (map[api.NodeAddressType][]string, error) {
+	value, ok := node.Annotations[AnnotationAddressOverride]
+	if !ok {
+		return nil, nil
+	}
+	overrides, err := parseTypedList(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, addresses := range overrides {
+		for _, address := range addresses {
+			if net.ParseIP(address) == nil {
+				return nil, fmt.Errorf("invalid address %q", address)
+			}
+		}
+	}
+	return overrides, nil
+}
+
+// extraNames returns the names of AnnotationExtraNames by address type.
+func extraNames(node *api.Node) (map[api.NodeAddressType][]string, error) {
+	value, ok := node.Annotations[AnnotationExtraNames]
+	if !ok {
+		return nil, nil
+	}
+	names, err := parseTypedList(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, list := range names {
+		for i, name := range list {
+			name = strings.ToLower(name)
+			if !strings.HasSuffix(name, ".") {
+				name += "."
+			}
+			list[i] = name
+		}
+	}
+	return names, nil
+}
+
+// nodeWeight returns the weight of AnnotationWeight, or DefaultWeight when it is missing.
+func nodeWeight(node *api.Node) (int64, error) {
+	value, ok := node.Annotations[AnnotationWeight]
+	if !ok {
+		return DefaultWeight, nil
+	}
+	weight, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || weight < 0 || weight > maxWeight {
+		return 0, fmt.Errorf("invalid weight %q, must be between 0 and %d", value, maxWeight)
+	}
+	return weight, nil
+}
+
+// annotationsChanged returns true when the annotations read by the Controller differ between old and cur.
+func annotationsChanged(old, cur *api.Node) bool {
+	return !reflect.DeepEqual(controllerAnnotations(old), controllerAnnotations(cur))
+}
+
+// controllerAnnotations returns the annotations of node read by the Controller.
+func controllerAnnotations(node *api.Node) map[string]string {
+	result := map[string]string{}
+	for k, v := range node.Annotations {
+		if strings.HasPrefix(k, annotationPrefix) {
+			result[k] = v
+		}
+	}
+	return result
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"google.golang.org/api/googleapi"
+
+	utilerrors "k8s.io/kubernetes/pkg/util/errors"
+	"k8s.io/kubernetes/pkg/util/wait"
+)
+
+const (
+	// DefaultRetryBackoff is the default delay before retrying the first failed sync.
+	DefaultRetryBackoff = time.Second
+
+	// DefaultMaxBackoff is the default maximum delay between retries of failed syncs.
+	DefaultMaxBackoff = 5 * time.Minute
+
+	// DefaultFailureThreshold is the default number of consecutive syncs failed
+	// with retryable errors after which the circuit breaker opens.
+	DefaultFailureThreshold = 10
+)
+
+// backoffJitter is the maximum factor by which a retry delay is randomly extended,
+// so that controllers sharing an account of the DNS Provider spread their retries.
+const backoffJitter = 0.2
+
+// isRetryable returns true when retrying err soon might succeed, e.g. after
+// throttling, server or network errors. Fatal errors, like a missing zone, a DNS
+// Provider lacking support for a feature or a request rejected by the DNS
+// Provider, are only retried after SyncInterval. An Aggregate is retryable when
+// one of its errors is.
+func isRetryable(err error) bool {
+	if agg, ok := err.(utilerrors.Aggregate); ok {
+		for _, x := range agg.Errors() {
+			if isRetryable(x) {
+				return true
+			}
+		}
+		return false
+	}
+	switch errorKind(err) {
+	case errorKindZoneNotFound, errorKindUnsupported:
+		return false
+	}
+	if e, ok := err.(*syncError); ok {
+		return isRetryable(e.err)
+	}
+	switch e := err.(type) {
+	case awserr.Error:
+		return isRetryableAWS(e)
+	case *googleapi.Error:
+		return isRetryableGoogle(e)
+	}
+	return true
+}
+
+// retryableAWSCodes are the error codes of AWS that are retryable despite a client error status.
+var retryableAWSCodes = map[string]bool{
+	"Throttling":              true,
+	"ThrottlingException":     true,
+	"RequestLimitExceeded":    true,
+	"PriorRequestNotComplete": true,
+}
+
+// isRetryableAWS returns true for throttling and server errors of AWS. Other
+// client errors, like AccessDenied or InvalidChangeBatch, are fatal.
+func isRetryableAWS(err awserr.Error) bool {
+	if retryableAWSCodes[err.Code()] {
+		return true
+	}
+	if e, ok := err.(awserr.RequestFailure); ok {
+		return e.StatusCode() >= 500
+	}
+	// Errors without a response, like network errors.
+	return true
+}
+
+// isRetryableGoogle returns true for throttling and server errors of Google APIs,
+// which report exceeded rate limits with status 403 or 429. Other client errors
+// are fatal.
+func isRetryableGoogle(err *googleapi.Error) bool {
+	for _, x := range err.Errors {
+		if x.Reason == "rateLimitExceeded" || x.Reason == "userRateLimitExceeded" {
+			return true
+		}
+	}
+	return err.Code == http.StatusTooManyRequests || err.Code >= 500
+}
+
+// circuitOpen returns true when the given number of consecutive syncs failed
+// with retryable errors reached FailureThreshold.
+func (c *Controller) circuitOpen(failures int) bool {
+	return c.failureThreshold > 0 && failures >= c.failureThreshold
+}
+
+// retryDelay returns the delay until the next sync after the given number of
+// consecutive failed syncs, of which the last one failed with err. Retryable
+// errors back off exponentially from RetryBackoff up to MaxBackoff. While the
+// circuit breaker is open a single sync probes the DNS Provider every MaxBackoff.
+func (c *Controller) retryDelay(failures int, err error) time.Duration {
+	if !isRetryable(err) {
+		return c.syncInterval
+	}
+	if c.circuitOpen(failures) {
+		return c.maxBackoff
+	}
+	delay := c.retryBackoff
+	for i := 1; i < failures && delay < c.maxBackoff; i++ {
+		delay *= 2
+	}
+	delay = wait.Jitter(delay, backoffJitter)
+	if delay > c.maxBackoff {
+		delay = c.maxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"google.golang.org/api/googleapi"
+
+	utilerrors "k8s.io/kubernetes/pkg/util/errors"
+)
+
+func TestIsRetryable(t *testing.T) {
+	awsFailure := func(code string, status int) error {
+		return awserr.NewRequestFailure(awserr.New(code, "failed", nil), status, "request")
+	}
+	testScenarios := []struct {
+		name   string
+		err    error
+		expect bool
+	}{
+		{name: "unknown", err: errors.New("failed"), expect: true},
+		{name: "zone not found", err: newSyncError(errorKindZoneNotFound, errors.New("failed")), expect: false},
+		{name: "unsupported", err: newSyncError(errorKindUnsupported, errors.New("failed")), expect: false},
+		{name: "aws throttling", err: newSyncError(errorKindApply, awsFailure("Throttling", 400)), expect: true},
+		{name: "aws prior request", err: newSyncError(errorKindApply, awsFailure("PriorRequestNotComplete", 400)), expect: true},
+		{name: "aws server error", err: newSyncError(errorKindProvider, awsFailure("ServiceUnavailable", 503)), expect: true},
+		{name: "aws network error", err: newSyncError(errorKindProvider, awserr.New("RequestError", "failed", nil)), expect: true},
+		{name: "aws access denied", err: newSyncError(errorKindProvider, awsFailure("AccessDenied", 403)), expect: false},
+		{name: "aws invalid change batch", err: newSyncError(errorKindApply, awsFailure("InvalidChangeBatch", 400)), expect: false},
+		{name: "google too many requests", err: newSyncError(errorKindApply, &googleapi.Error{Code: 429}), expect: true},
+		{name: "google server error", err: newSyncError(errorKindApply, &googleapi.Error{Code: 500}), expect: true},
+		{name: "google rate limit", err: newSyncError(errorKindApply, &googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}}}), expect: true},
+		{name: "google forbidden", err: newSyncError(errorKindProvider, &googleapi.Error{Code: 403}), expect: false},
+		{name: "google bad request", err: newSyncError(errorKindApply, &googleapi.Error{Code: 400}), expect: false},
+		{name: "zone creation throttled", err: newSyncErrorf(errorKindZoneCreation, awsFailure("Throttling", 400), "failed to create Zone %q", "test.com."), expect: true},
+		{name: "zone creation denied", err: newSyncErrorf(errorKindZoneCreation, awsFailure("AccessDenied", 403), "failed to create Zone %q", "test.com."), expect: false},
+		{name: "aggregate", err: utilerrors.NewAggregate([]error{awsFailure("AccessDenied", 403), awsFailure("Throttling", 400)}), expect: true},
+		{name: "fatal aggregate", err: utilerrors.NewAggregate([]error{awsFailure("AccessDenied", 403), &googleapi.Error{Code: 400}}), expect: false},
+	}
+	for _, x := range testScenarios {
+		if got := isRetryable(x.err); got != x.expect {
+			t.Errorf("%s: expected retryable %v, got %v", x.name, x.expect, got)
+		}
+	}
+}
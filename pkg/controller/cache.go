@@ -0,0 +1,77 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"time"
+
+	"k8s.io/kubernetes/federation/pkg/dnsprovider"
+
+	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/routing"
+)
+
+// recordCache is the view of the Records of a zone at the DNS Provider, which
+// is kept up to date with the applied Changes between drift checks.
+type recordCache struct {
+	rrs      dnsprovider.ResourceRecordSets
+	records  []dnsprovider.ResourceRecordSet
+	listedAt time.Time
+}
+
+// newRecordCache creates a cache of the listed records of rrs.
+func newRecordCache(rrs dnsprovider.ResourceRecordSets, records []dnsprovider.ResourceRecordSet) *recordCache {
+	return &recordCache{rrs: rrs, records: records, listedAt: time.Now()}
+}
+
+// fresh returns true when the Records were listed within driftCheckInterval.
+func (r *recordCache) fresh(driftCheckInterval time.Duration) bool {
+	return r != nil && driftCheckInterval > 0 && time.Since(r.listedAt) < driftCheckInterval
+}
+
+// apply updates the cached Records with the applied changes.
+func (r *recordCache) apply(changes []Change) {
+	for _, change := range changes {
+		switch change.Action {
+		case ActionAdd:
+			r.records = append(r.records, change.record)
+		case ActionRemove:
+			r.remove(change.record)
+		case ActionUpdate:
+			r.remove(change.old)
+			r.records = append(r.records, change.record)
+		}
+	}
+}
+
+// remove removes the cached Record with the name, type and set identifier of record.
+func (r *recordCache) remove(record dnsprovider.ResourceRecordSet) {
+	for i, x := range r.records {
+		if routing.Key(x) == routing.Key(record) {
+			r.records = append(r.records[:i:i], r.records[i+1:]...)
+			return
+		}
+	}
+}
+
+// needsZoneList returns true when the cache of a zone must be refreshed from the DNS Provider.
+func (c *Controller) needsZoneList() bool {
+	for _, z := range c.zones {
+		if !z.cache.fresh(c.driftCheckInterval) {
+			return true
+		}
+	}
+	return false
+}
+
+// invalidateCaches forces listing the Records of all zones and the health checks in the next sync.
+func (c *Controller) invalidateCaches() {
+	for _, z := range c.zones {
+		z.cache = nil
+	}
+	c.healthChecks = nil
+}
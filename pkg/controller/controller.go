@@ -15,11 +15,13 @@ import (
 
 	"k8s.io/kubernetes/federation/pkg/dnsprovider"
 	"k8s.io/kubernetes/pkg/api"
-	"k8s.io/kubernetes/pkg/client/cache"
+	"k8s.io/kubernetes/pkg/client/leaderelection"
+	"k8s.io/kubernetes/pkg/client/record"
 	"k8s.io/kubernetes/pkg/client/unversioned"
 	"k8s.io/kubernetes/pkg/labels"
 
 	k8sutil "github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes"
+	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/routing"
 )
 
 // Options for creating a new Controller.
@@ -27,15 +29,62 @@ type Options struct {
 	// DNSProvider is the provider for dns services, required.
 	DNSProvider dnsprovider.Interface
 
-	// ZoneName, like "example.com.", required.
+	// DNSProviderName, like "aws-route53", names the DNS Provider in messages,
+	// defaults to the type of DNSProvider.
+	DNSProviderName string
+
+	// ZoneName, like "example.com.", required when Zones is empty.
 	ZoneName string
 
 	// TTL value of Records, defaults to 60
 	TTL int64
 
+	// Zones are additional zones with their own settings. The fields ZoneName, TTL,
+	// AddressTypes, ApexAddressType, Selector, RecordNameTemplate, NodeRecords,
+	// GroupByLabel, TopologyRecords, LatencyRouting, WeightedRecords, SyncServices
+	// and SyncIngresses only configure the zone of ZoneName.
+	Zones []ZoneOptions
+
+	// CreateZone enables creating zones that do not exist at the DNS Provider
+	// instead of waiting until they are created.
+	CreateZone bool
+
+	// DryRun computes and logs the Changes of each sync without applying them.
+	DryRun bool
+
 	// SyncInterval is the interval for syncing with the DNS Provider, defaults to 60 seconds.
 	SyncInterval time.Duration
 
+	// DriftCheckInterval enables caching the Records of the zones between syncs.
+	// Syncs compute their Changes against the cache and only list the zones and
+	// Records from the DNS Provider after DriftCheckInterval to detect changes
+	// made by others. Defaults to 0, which lists them in every sync.
+	DriftCheckInterval time.Duration
+
+	// Debounce is the time a requested sync waits for further changes, so that
+	// a burst of changes, like adding many Nodes, results in a single sync.
+	Debounce time.Duration
+
+	// MinSyncInterval is the minimum time between the start of two syncs.
+	MinSyncInterval time.Duration
+
+	// RetryBackoff is the delay before retrying a sync that failed with a retryable
+	// error, it doubles with each consecutive failure. Defaults to DefaultRetryBackoff.
+	RetryBackoff time.Duration
+
+	// MaxBackoff is the maximum delay between retries of failed syncs, defaults to DefaultMaxBackoff.
+	MaxBackoff time.Duration
+
+	// FailureThreshold is the number of consecutive syncs failed with retryable
+	// errors after which the circuit breaker opens: until a sync succeeds, syncs are
+	// only attempted every MaxBackoff, requested syncs are ignored and Ready reports
+	// an error. Defaults to DefaultFailureThreshold, a negative value disables it.
+	FailureThreshold int
+
+	// LivenessFactor is the number of SyncIntervals without a completed sync attempt
+	// after which Healthy reports an error, defaults to DefaultLivenessFactor.
+	LivenessFactor int
+
 	// Client is the Kubernetes Client to use or use default when nil.
 	Client unversioned.Interface
 
@@ -49,6 +98,99 @@ type Options struct {
 
 	// Selector to target only specific Nodes.
 	Selector labels.Selector
+
+	// NodeEligibility excludes Ready Nodes from the Records, e.g. while they are drained.
+	// Nodes annotated with AnnotationExclude are always excluded.
+	NodeEligibility NodeEligibility
+
+	// KeepStaleRecords disables the removal of managed Records that are no longer
+	// desired, e.g. when an address type has no Ready Nodes left.
+	KeepStaleRecords bool
+
+	// RecordNameTemplate is a text/template for the names of the address type Records,
+	// defaults to DefaultRecordNameTemplate. It can access the fields AddressType,
+	// NodeName, Labels and Zone, e.g. "{{.Labels.pool}}.nodes.{{.Zone}}".
+	RecordNameTemplate string
+
+	// IPFamily selects whether IPv4 addresses are synced to A Records, IPv6 addresses
+	// to AAAA Records or both, defaults to DualStack.
+	IPFamily IPFamily
+
+	// NodeRecords enables publishing an additional Record per Node,
+	// like "node1.externalip.example.com.".
+	NodeRecords bool
+
+	// GroupByLabel enables publishing an additional Record per value of the Node label,
+	// like "pool-a.externalip.example.com." for Nodes labeled with
+	// "cloud.google.com/gke-nodepool=pool-a". Nodes without the label are only
+	// published in the Records of all Nodes.
+	GroupByLabel string
+
+	// TopologyRecords enables publishing an additional Record per value of the
+	// failure-domain.beta.kubernetes.io/zone and region labels of the Nodes,
+	// like "us-east-1a.externalip.example.com." and "us-east-1.externalip.example.com.".
+	TopologyRecords bool
+
+	// LatencyRouting publishes the address type and apex Records once per region
+	// of the Nodes with a latency routing policy, so that resolvers get the Nodes
+	// of the nearest region. It requires a DNS Provider implementing routing.Router,
+	// like aws-route53, and Nodes without a region label are skipped.
+	LatencyRouting bool
+
+	// WeightedRecords publishes the address type and apex Records once per Node
+	// with a weighted routing policy, which is DefaultWeight or the value of
+	// AnnotationWeight. It requires a DNS Provider implementing routing.Router,
+	// like aws-route53, and can't be combined with LatencyRouting.
+	WeightedRecords bool
+
+	// HealthCheckPort enables binding each weighted Record to a TCP health check
+	// of the first address of its Node at the port, so that resolvers don't get
+	// unhealthy Nodes between syncs. It requires a DNS Provider implementing
+	// routing.HealthChecker. Health checks are tagged with the OwnerID and removed
+	// when no Record uses them anymore.
+	HealthCheckPort int
+
+	// MaxAddressesPerRecord limits the number of addresses of each A and AAAA Record,
+	// so that responses fit into a UDP packet of 512 bytes. The addresses are chosen
+	// by rendezvous hashing on the Node names, which keeps the selection stable while
+	// unrelated Nodes change. Defaults to 0, which publishes all addresses.
+	MaxAddressesPerRecord int
+
+	// SyncServices enables syncing of Services annotated with AnnotationPublish
+	// to Records like "<service>.<namespace>.example.com.".
+	SyncServices bool
+
+	// SyncIngresses enables syncing of the hosts of Ingresses that are inside of the zone.
+	SyncIngresses bool
+
+	// ServiceAddressType is the address type of the Nodes published for NodePort Services
+	// and Ingresses without load balancer IPs, defaults to NodeExternalIP.
+	ServiceAddressType api.NodeAddressType
+
+	// OwnerID identifies this Controller in the ownership TXT Records, defaults to "default".
+	// Controllers sharing a zone must use different ids.
+	OwnerID string
+
+	// AdoptExisting takes over desired names that have address Records but no
+	// ownership Record, like Records created by versions without ownership, by
+	// adding an ownership Record. Their A and AAAA Records are managed afterwards.
+	AdoptExisting bool
+
+	// LeaderElection enables running several replicas of which only the elected
+	// leader syncs to DNS when not nil.
+	LeaderElection *LeaderElectionOptions
+
+	// Events enables emitting Kubernetes Events about Record changes on the Nodes
+	// whose addresses were added or removed.
+	Events bool
+
+	// EventObject, like the Pod of the Controller, additionally receives Events
+	// about all Record changes and failed syncs when Events is set.
+	EventObject *api.ObjectReference
+
+	// Sources are additional Sources of Endpoints. Sources implementing Runner
+	// are started by the Controller.
+	Sources []Source
 }
 
 // New creates a new Controller.
@@ -57,26 +199,47 @@ func New(opts *Options) (*Controller, error) {
 	if opts.DNSProvider == nil {
 		return nil, fmt.Errorf("please provide a DNS Provider")
 	}
-	if opts.ZoneName == "" {
+	zones := zoneOptions(opts)
+	if len(zones) == 0 {
 		return nil, fmt.Errorf("please provide a zone name")
 	}
-	if (opts.AddressTypes == nil || len(opts.AddressTypes) == 0) && opts.ApexAddressType == "" {
-		return nil, fmt.Errorf("please provide either AddressTypes or ApexAddressType")
+
+	c.ipFamily = opts.IPFamily
+	if c.ipFamily == "" {
+		c.ipFamily = DualStack
+	}
+	if err := c.ipFamily.validate(); err != nil {
+		return nil, err
 	}
 
 	c.dns = opts.DNSProvider
-	c.ttl = opts.TTL
-	c.zoneName = opts.ZoneName
+	c.dnsProvider = opts.DNSProviderName
+	if c.dnsProvider == "" {
+		c.dnsProvider = fmt.Sprintf("%T", opts.DNSProvider)
+	}
 	c.client = opts.Client
-	c.addressTypes = opts.AddressTypes
-	c.apexAddressType = opts.ApexAddressType
-	c.selector = opts.Selector
+	c.keepStaleRecords = opts.KeepStaleRecords
+	c.eligibility = opts.NodeEligibility
+	c.createZone = opts.CreateZone
+	c.dryRun = opts.DryRun
+	c.ownerID = opts.OwnerID
+	c.adoptExisting = opts.AdoptExisting
 	c.syncInterval = opts.SyncInterval
+	c.livenessFactor = opts.LivenessFactor
+	c.driftCheckInterval = opts.DriftCheckInterval
+	c.debounce = opts.Debounce
+	c.minSyncInterval = opts.MinSyncInterval
+	c.retryBackoff = opts.RetryBackoff
+	c.maxBackoff = opts.MaxBackoff
+	c.failureThreshold = opts.FailureThreshold
+	c.healthCheckPort = opts.HealthCheckPort
+	c.maxAddresses = opts.MaxAddressesPerRecord
 	c.stopCh = make(chan struct{})
-	c.syncCh = make(chan struct{})
+	// A request during a sync or while waiting for leadership stays pending.
+	c.syncCh = make(chan struct{}, 1)
 	c.log = logrus.StandardLogger()
-	if c.ttl == 0 {
-		c.ttl = 60
+	if c.ownerID == "" {
+		c.ownerID = "default"
 	}
 	if c.client == nil {
 		client, err := k8sutil.NewKubeClient()
@@ -88,67 +251,258 @@ func New(opts *Options) (*Controller, error) {
 	if c.syncInterval == 0 {
 		c.syncInterval = time.Second * 60
 	}
+	if c.livenessFactor == 0 {
+		c.livenessFactor = DefaultLivenessFactor
+	}
+	if c.retryBackoff == 0 {
+		c.retryBackoff = DefaultRetryBackoff
+	}
+	if c.maxBackoff == 0 {
+		c.maxBackoff = DefaultMaxBackoff
+	}
+	if c.failureThreshold == 0 {
+		c.failureThreshold = DefaultFailureThreshold
+	}
+	if c.maxAddresses < 0 {
+		return nil, fmt.Errorf("MaxAddressesPerRecord must not be negative")
+	}
+	if c.healthCheckPort != 0 {
+		if _, ok := c.dns.(routing.HealthChecker); !ok {
+			return nil, fmt.Errorf("DNS Provider doesn't support health checks")
+		}
+	}
+
+	serviceAddressType := opts.ServiceAddressType
+	if serviceAddressType == "" {
+		serviceAddressType = api.NodeExternalIP
+	}
+	c.nodes = newNodeWatcher(c.client, nodeSelector(zones), c.log)
+	for _, zoneOpts := range zones {
+		z, err := c.newZone(zoneOpts, serviceAddressType)
+		if err != nil {
+			return nil, err
+		}
+		c.zones = append(c.zones, z)
+	}
+	c.sources = opts.Sources
+	if opts.Events {
+		c.eventBroadcaster, c.recorder = newEventRecorder()
+		c.eventObject = opts.EventObject
+	}
+	if opts.LeaderElection != nil {
+		e, err := c.newElector(*opts.LeaderElection)
+		if err != nil {
+			return nil, err
+		}
+		c.elector = e
+	}
 	return c, nil
 }
 
 // Controller syncs Kubernetes Node IPs to a DNS service.
 type Controller struct {
-	dns             dnsprovider.Interface
-	dnsProvider     string
-	zoneName        string
-	ttl             int64
-	syncInterval    time.Duration
-	log             *logrus.Logger
-	stopCh          chan struct{}
-	syncCh          chan struct{}
-	client          unversioned.Interface
-	addressTypes    []api.NodeAddressType
-	apexAddressType api.NodeAddressType
-	cache           cache.Store
-	selector        labels.Selector
+	dns                dnsprovider.Interface
+	dnsProvider        string
+	syncInterval       time.Duration
+	log                *logrus.Logger
+	stopCh             chan struct{}
+	syncCh             chan struct{}
+	client             unversioned.Interface
+	keepStaleRecords   bool
+	eligibility        NodeEligibility
+	createZone         bool
+	dryRun             bool
+	ownerID            string
+	adoptExisting      bool
+	ipFamily           IPFamily
+	zones              []*zone
+	nodes              *watcher
+	services           *watcher
+	ingresses          *watcher
+	sources            []Source
+	livenessFactor     int
+	driftCheckInterval time.Duration
+	debounce           time.Duration
+	minSyncInterval    time.Duration
+	retryBackoff       time.Duration
+	maxBackoff         time.Duration
+	failureThreshold   int
+	healthCheckPort    int
+	healthChecks       map[routing.HealthCheckTarget]string
+	boundHealthChecks  map[string]bool
+	maxAddresses       int
+	health             health
+	elector            *leaderelection.LeaderElector
+	identity           string
+	eventBroadcaster   record.EventBroadcaster
+	recorder           record.EventRecorder
+	eventObject        *api.ObjectReference
 }
 
 // Run starts the Controller Controller in an endless loop.
 func (c *Controller) Run() error {
-	c.watch()
-	c.loop()
+	if w := c.startEvents(); w != nil {
+		defer w.Stop()
+	}
+	c.startWatchers(c.stopCh)
+	for _, w := range c.watchers() {
+		go c.watchChanges(w.Changes())
+	}
+	for _, source := range c.sources {
+		go c.watchChanges(source.Changes())
+	}
+	if c.elector != nil {
+		c.runElected(c.stopCh)
+		return nil
+	}
+	if !c.waitForWatchers(c.stopCh) {
+		return nil
+	}
+	c.setLeading(true)
+	c.loop(c.stopCh)
 	return nil
 }
 
+// watchers returns the watchers in use.
+func (c *Controller) watchers() []*watcher {
+	var result []*watcher
+	for _, w := range []*watcher{c.nodes, c.services, c.ingresses} {
+		if w != nil {
+			result = append(result, w)
+		}
+	}
+	return result
+}
+
+// startWatchers starts the watchers and the Sources implementing Runner until stopCh is closed.
+func (c *Controller) startWatchers(stopCh <-chan struct{}) {
+	for _, w := range c.watchers() {
+		w.Run(stopCh)
+	}
+	for _, source := range c.sources {
+		if runner, ok := source.(Runner); ok {
+			runner.Run(stopCh)
+		}
+	}
+}
+
+// waitForWatchers blocks until all watchers completed their initial listing, so
+// that no Records are removed for objects that were not listed yet. It returns
+// false when stopCh was closed before.
+func (c *Controller) waitForWatchers(stopCh <-chan struct{}) bool {
+	for _, w := range c.watchers() {
+		for !w.HasSynced() {
+			select {
+			case <-stopCh:
+				return false
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+	return true
+}
+
 // Stop will unblock Run(). Only call this once.
 func (c *Controller) Stop() {
 	close(c.stopCh)
 }
 
 // loop blocks and run sync when it is request through
-// syncCh or when syncInterval has passed.
-func (c *Controller) loop() {
+// syncCh or when syncInterval has passed until stopCh is closed.
+// Failed syncs are retried with backoff, see retryDelay. Requested
+// syncs are ignored while backing off and wait for the Debounce window
+// and MinSyncInterval, so that a burst of requests results in one sync.
+func (c *Controller) loop(stopCh <-chan struct{}) {
 	timer := time.NewTimer(c.syncInterval)
+	failures := 0
+	var retryAt, lastSync time.Time
+	// pending fires when a requested sync is due.
+	var pending <-chan time.Time
 	sync := func() {
+		pending = nil
+		lastSync = time.Now()
 		err := c.sync()
+		interval := c.syncInterval
+		retryAt = time.Time{}
 		if err != nil {
 			c.log.Error(err)
+			c.recordSyncFailure(err)
+			// Fatal errors need an operator and don't count towards the circuit breaker.
+			retryable := isRetryable(err)
+			if retryable {
+				failures++
+			}
+			interval = c.retryDelay(failures, err)
+			switch {
+			case !retryable:
+				c.log.Infof("Sync failed with a fatal error, syncing again in %v", interval)
+			case c.circuitOpen(failures):
+				if failures == c.failureThreshold {
+					c.log.Errorf("Circuit breaker open after %d consecutive failed syncs", failures)
+				}
+				retryAt = time.Now().Add(interval)
+				c.log.Infof("Circuit breaker open, probing with a sync in %v", interval)
+			default:
+				retryAt = time.Now().Add(interval)
+				c.log.Infof("Retrying failed sync in %v", interval)
+			}
+		} else {
+			failures = 0
 		}
-		timer.Reset(c.syncInterval)
+		consecutiveFailures.Set(float64(failures))
+		c.health.recordAttempt(err, failures, interval)
+		timer.Reset(interval)
 	}
 L:
 	for {
 		select {
-		case <-c.stopCh:
+		case <-stopCh:
 			timer.Stop()
 			break L
 		case <-timer.C:
+			if wait := c.minSyncInterval - time.Since(lastSync); wait > 0 {
+				timer.Reset(wait)
+				continue
+			}
 			sync()
 		case <-c.syncCh:
+			if time.Now().Before(retryAt) {
+				c.log.Debugf("Backing off, ignoring requested sync")
+				continue
+			}
+			if pending != nil {
+				coalescedEvents.Inc()
+				continue
+			}
+			delay := c.debounce
+			if wait := c.minSyncInterval - time.Since(lastSync); wait > delay {
+				delay = wait
+			}
+			pending = time.After(delay)
+		case <-pending:
 			sync()
 		}
 	}
 }
 
-// requestSync will trigger a sync in the next loop iteration.
+// watchChanges requests a sync whenever changes reports a change.
+func (c *Controller) watchChanges(changes <-chan struct{}) {
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-changes:
+			c.requestSync()
+		}
+	}
+}
+
+// requestSync will trigger a sync in the next loop iteration. A request is
+// merged with a pending one that the loop did not receive yet.
 func (c *Controller) requestSync() {
 	select {
 	case c.syncCh <- struct{}{}:
 	default:
+		coalescedEvents.Inc()
 	}
 }
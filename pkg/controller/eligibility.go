@@ -0,0 +1,97 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/kubernetes/pkg/api"
+
+	k8sutil "github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes"
+)
+
+// AnnotationExclude excludes a Node from all Records when set to "true",
+// e.g. during maintenance.
+const AnnotationExclude = "kube-dns-sync/exclude"
+
+// AnnotationInclude opts a Node in when set to "true", see NodeEligibility.RequireInclude.
+const AnnotationInclude = "kube-dns-sync/include"
+
+// NodeEligibility configures which Ready Nodes are excluded from the Records.
+type NodeEligibility struct {
+	// RequireInclude excludes all Nodes that are not annotated with AnnotationInclude.
+	RequireInclude bool
+
+	// ExcludeUnschedulable excludes cordoned and draining Nodes.
+	ExcludeUnschedulable bool
+
+	// ExcludeTaintEffects excludes Nodes with a taint of one of the effects, like "NoSchedule".
+	ExcludeTaintEffects []api.TaintEffect
+
+	// ExcludeConditions excludes Nodes for which one of the conditions is true,
+	// like "DiskPressure" or "NetworkUnavailable".
+	ExcludeConditions []api.NodeConditionType
+}
+
+// ineligibility returns why the Ready node is excluded from the Records,
+// or an empty string when it is eligible. Invalid taints are logged and ignored.
+func (e NodeEligibility) ineligibility(node *api.Node, log *logrus.Logger) string {
+	if node.Annotations[AnnotationExclude] == "true" {
+		return fmt.Sprintf("annotated with %s", AnnotationExclude)
+	}
+	if e.RequireInclude && node.Annotations[AnnotationInclude] != "true" {
+		return fmt.Sprintf("not annotated with %s", AnnotationInclude)
+	}
+	if e.ExcludeUnschedulable && node.Spec.Unschedulable {
+		return "unschedulable"
+	}
+	if len(e.ExcludeTaintEffects) > 0 {
+		taints, err := api.GetTaintsFromNodeAnnotations(node.Annotations)
+		if err != nil {
+			log.Warnf("Ignoring taints of Node %q: %v", node.Name, err)
+		}
+		for _, taint := range taints {
+			for _, effect := range e.ExcludeTaintEffects {
+				if taint.Effect == effect {
+					return fmt.Sprintf("tainted with %s=%s:%s", taint.Key, taint.Value, taint.Effect)
+				}
+			}
+		}
+	}
+	for _, cond := range node.Status.Conditions {
+		for _, excluded := range e.ExcludeConditions {
+			if cond.Type == excluded && cond.Status == api.ConditionTrue {
+				return fmt.Sprintf("condition %s is true", cond.Type)
+			}
+		}
+	}
+	return ""
+}
+
+// eligibilityChanged returns true when a change from old to cur might change the
+// readiness or eligibility of a Node. Heartbeats of the conditions are ignored.
+func eligibilityChanged(old, cur *api.Node) bool {
+	return k8sutil.IsNodeReady(old) != k8sutil.IsNodeReady(cur) ||
+		old.Spec.Unschedulable != cur.Spec.Unschedulable ||
+		old.Annotations[AnnotationExclude] != cur.Annotations[AnnotationExclude] ||
+		old.Annotations[AnnotationInclude] != cur.Annotations[AnnotationInclude] ||
+		old.Annotations[api.TaintsAnnotationKey] != cur.Annotations[api.TaintsAnnotationKey] ||
+		!reflect.DeepEqual(conditionStatuses(old), conditionStatuses(cur))
+}
+
+// conditionStatuses returns the status of each condition of node.
+func conditionStatuses(node *api.Node) map[api.NodeConditionType]api.ConditionStatus {
+	result := map[api.NodeConditionType]api.ConditionStatus{}
+	for _, cond := range node.Status.Conditions {
+		result[cond.Type] = cond.Status
+	}
+	return result
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"strings"
+
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/client/record"
+	"k8s.io/kubernetes/pkg/runtime"
+	"k8s.io/kubernetes/pkg/types"
+	"k8s.io/kubernetes/pkg/watch"
+
+	netutil "github.com/wikiwi/kube-dns-sync/pkg/util/net"
+)
+
+// Reasons of the Events emitted by the Controller.
+const (
+	// EventReasonRecordAdded is emitted on the EventObject when a Record was added.
+	EventReasonRecordAdded = "DNSRecordAdded"
+	// EventReasonRecordRemoved is emitted on the EventObject when a Record was removed.
+	EventReasonRecordRemoved = "DNSRecordRemoved"
+	// EventReasonRecordUpdated is emitted on the EventObject when a Record was updated.
+	EventReasonRecordUpdated = "DNSRecordUpdated"
+	// EventReasonAddressAdded is emitted on a Node when one of its addresses was added to a Record.
+	EventReasonAddressAdded = "DNSAddressAdded"
+	// EventReasonAddressRemoved is emitted on a Node when one of its addresses was removed from a Record.
+	EventReasonAddressRemoved = "DNSAddressRemoved"
+	// EventReasonSyncFailed is emitted on the EventObject when a sync failed.
+	EventReasonSyncFailed = "DNSSyncFailed"
+)
+
+// eventReasons maps the Actions to the reasons of their Events.
+var eventReasons = map[Action]string{
+	ActionAdd:    EventReasonRecordAdded,
+	ActionRemove: EventReasonRecordRemoved,
+	ActionUpdate: EventReasonRecordUpdated,
+}
+
+// newEventRecorder creates a recorder for Events of the Controller.
+func newEventRecorder() (record.EventBroadcaster, record.EventRecorder) {
+	broadcaster := record.NewBroadcaster()
+	recorder := broadcaster.NewRecorder(api.EventSource{Component: "kube-dns-sync"})
+	return broadcaster, recorder
+}
+
+// startEvents starts sending the recorded Events to the Kubernetes API.
+// Stop the returned watch to stop sending.
+func (c *Controller) startEvents() watch.Interface {
+	if c.eventBroadcaster == nil {
+		return nil
+	}
+	return c.eventBroadcaster.StartRecordingToSink(c.client.Events(api.NamespaceAll))
+}
+
+// eventf emits an Event on object when Events are enabled.
+func (c *Controller) eventf(object runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	if c.recorder == nil || object == nil {
+		return
+	}
+	c.recorder.Eventf(object, eventType, reason, messageFmt, args...)
+}
+
+// recordChangeEvents emits the Events of an applied change on the EventObject
+// and on the Nodes whose addresses were added to or removed from the Record.
+// Changes of ownership Records accompany the changes of the addresses and
+// don't emit Events of their own.
+func (c *Controller) recordChangeEvents(change Change) {
+	if c.recorder == nil || isOwnershipChange(change) {
+		return
+	}
+	if c.eventObject != nil {
+		c.eventf(c.eventObject, api.EventTypeNormal, eventReasons[change.Action], "%s", change)
+	}
+
+	var added, removed []string
+	switch change.Action {
+	case ActionAdd:
+		added = change.Rrdatas
+	case ActionRemove:
+		removed = change.Rrdatas
+	case ActionUpdate:
+		added = difference(change.Rrdatas, change.OldRrdatas)
+		removed = difference(change.OldRrdatas, change.Rrdatas)
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+	nodes := c.nodesByAddress()
+	for _, address := range added {
+		for _, node := range nodes[address] {
+			c.eventf(nodeReference(node), api.EventTypeNormal, EventReasonAddressAdded,
+				"Added address %s to %s Record %q", address, change.Type, change.Name)
+		}
+	}
+	for _, address := range removed {
+		for _, node := range nodes[address] {
+			c.eventf(nodeReference(node), api.EventTypeNormal, EventReasonAddressRemoved,
+				"Removed address %s from %s Record %q", address, change.Type, change.Name)
+		}
+	}
+}
+
+// isOwnershipChange returns true when change adds, removes or updates an ownership Record.
+func isOwnershipChange(change Change) bool {
+	return change.Type == string(txtType) && strings.HasPrefix(change.Name, ownershipPrefix)
+}
+
+// recordSyncFailure emits an Event about err on the EventObject.
+func (c *Controller) recordSyncFailure(err error) {
+	if c.eventObject != nil {
+		c.eventf(c.eventObject, api.EventTypeWarning, EventReasonSyncFailed, "Sync failed: %v", err)
+	}
+}
+
+// nodesByAddress returns the watched Nodes by each of their addresses,
+// including the addresses of AnnotationAddressOverride.
+func (c *Controller) nodesByAddress() map[string][]*api.Node {
+	result := map[string][]*api.Node{}
+	for _, x := range c.nodes.List() {
+		node := x.(*api.Node)
+		for _, address := range node.Status.Addresses {
+			normalized := netutil.NormalizeAddress(address.Address)
+			result[normalized] = append(result[normalized], node)
+		}
+		overrides, _ := addressOverrides(node)
+		for _, addresses := range overrides {
+			for _, address := range addresses {
+				normalized := netutil.NormalizeAddress(address)
+				result[normalized] = append(result[normalized], node)
+			}
+		}
+	}
+	return result
+}
+
+// nodeReference returns a reference to node for emitting Events. Like the
+// kubelet it uses the name as uid, so that the Events show up in kubectl describe.
+func nodeReference(node *api.Node) *api.ObjectReference {
+	return &api.ObjectReference{
+		Kind: "Node",
+		Name: node.Name,
+		UID:  types.UID(node.Name),
+	}
+}
+
+// difference returns the elements of a that are not in b.
+func difference(a, b []string) []string {
+	var result []string
+	for _, x := range a {
+		found := false
+		for _, y := range b {
+			if x == y {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, x)
+		}
+	}
+	return result
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"fmt"
+
+	"k8s.io/kubernetes/federation/pkg/dnsprovider/rrstype"
+)
+
+// IPFamily selects the address families that are synced to DNS.
+type IPFamily string
+
+const (
+	// IPv4 only syncs IPv4 addresses to A Records.
+	IPv4 IPFamily = "ipv4"
+	// IPv6 only syncs IPv6 addresses to AAAA Records.
+	IPv6 IPFamily = "ipv6"
+	// DualStack syncs IPv4 addresses to A Records and IPv6 addresses to AAAA Records.
+	DualStack IPFamily = "dual"
+)
+
+// validate returns an error when f is not a known IPFamily.
+func (f IPFamily) validate() error {
+	switch f {
+	case IPv4, IPv6, DualStack:
+		return nil
+	}
+	return fmt.Errorf("invalid IP family %q", f)
+}
+
+// includes returns true when Records of recordType are synced for f.
+func (f IPFamily) includes(recordType rrstype.RrsType) bool {
+	switch recordType {
+	case rrstype.A:
+		return f == IPv4 || f == DualStack
+	case rrstype.AAAA:
+		return f == IPv6 || f == DualStack
+	}
+	return false
+}
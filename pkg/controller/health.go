@@ -0,0 +1,109 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultLivenessFactor is the default number of sync intervals without
+// a completed sync attempt after which the Controller is unhealthy.
+const DefaultLivenessFactor = 3
+
+// health tracks the sync attempts of the Controller.
+type health struct {
+	mu          sync.Mutex
+	started     time.Time
+	lastAttempt time.Time
+	lastErr     error
+	leading     bool
+	// failures is the number of consecutive failed syncs and interval
+	// the delay until the next sync attempt.
+	failures int
+	interval time.Duration
+}
+
+// setLeading records whether the sync loop runs. Starting the sync loop
+// restarts the liveness and readiness tracking.
+func (h *health) setLeading(leading bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leading = leading
+	if leading {
+		h.started = time.Now()
+		h.lastAttempt = time.Time{}
+		h.lastErr = nil
+		h.failures = 0
+		h.interval = 0
+	}
+}
+
+// recordAttempt records the result of a completed sync attempt, the number
+// of consecutive failures and the delay until the next attempt.
+func (h *health) recordAttempt(err error, failures int, interval time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastAttempt = time.Now()
+	h.lastErr = err
+	h.failures = failures
+	h.interval = interval
+}
+
+// Healthy returns an error when the sync loop did not complete a sync attempt
+// within the last LivenessFactor sync intervals, e.g. because a call to the DNS
+// Provider hangs. Failed syncs don't affect the health, as a restart would not
+// fix them. Standbys waiting for leadership are always healthy.
+func (c *Controller) Healthy() error {
+	c.health.mu.Lock()
+	defer c.health.mu.Unlock()
+	if c.health.started.IsZero() || (c.elector != nil && !c.health.leading) {
+		return nil
+	}
+	last := c.health.lastAttempt
+	if last.IsZero() {
+		last = c.health.started
+	}
+	interval := c.syncInterval
+	if c.health.interval > interval {
+		interval = c.health.interval
+	}
+	threshold := time.Duration(c.livenessFactor) * interval
+	if since := time.Since(last); since > threshold {
+		return fmt.Errorf("no sync attempt completed for %v", since)
+	}
+	return nil
+}
+
+// Ready returns an error until the Kubernetes API was listed and the last
+// sync succeeded, naming the open circuit breaker after FailureThreshold
+// consecutive retryable failures. Standbys are ready as soon as the Kubernetes
+// API was listed.
+func (c *Controller) Ready() error {
+	for _, w := range c.watchers() {
+		if !w.HasSynced() {
+			return fmt.Errorf("waiting for the %s watcher to sync", w.name)
+		}
+	}
+	c.health.mu.Lock()
+	defer c.health.mu.Unlock()
+	if c.elector != nil && !c.health.leading {
+		return nil
+	}
+	if c.health.lastAttempt.IsZero() {
+		return fmt.Errorf("waiting for the first sync")
+	}
+	if c.circuitOpen(c.health.failures) {
+		return fmt.Errorf("circuit breaker open after %d consecutive failed syncs: %v", c.health.failures, c.health.lastErr)
+	}
+	if c.health.lastErr != nil {
+		return fmt.Errorf("last sync failed: %v", c.health.lastErr)
+	}
+	return nil
+}
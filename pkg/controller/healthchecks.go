@@ -0,0 +1,118 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"k8s.io/kubernetes/federation/pkg/dnsprovider/rrstype"
+
+	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/routing"
+	netutil "github.com/wikiwi/kube-dns-sync/pkg/util/net"
+)
+
+// bindHealthChecks binds the weighted endpoints to the health checks of their
+// first address at HealthCheckPort. A weighted endpoint with IPv4 and IPv6
+// addresses is split into one endpoint per address family, so that the A and
+// AAAA Records are bound to the health check of an address of their own family.
+// Missing health checks are created when create is set. The ids of the bound
+// health checks are kept for removeStaleHealthChecks.
+func (c *Controller) bindHealthChecks(endpoints map[*zone][]Endpoint, create bool) error {
+	c.boundHealthChecks = nil
+	if c.healthCheckPort == 0 {
+		return nil
+	}
+	ids, err := c.listHealthChecks()
+	if err != nil {
+		return err
+	}
+	checker := c.dns.(routing.HealthChecker)
+
+	bound := map[string]bool{}
+	for z, list := range endpoints {
+		var result []Endpoint
+		for _, endpoint := range list {
+			if !endpoint.Policy.Weighted {
+				result = append(result, endpoint)
+				continue
+			}
+			byType := map[rrstype.RrsType][]string{}
+			for _, address := range c.filterAddresses(endpoint.Targets) {
+				recordType, _ := netutil.RecordType(address)
+				byType[recordType] = append(byType[recordType], address)
+			}
+			for _, recordType := range []rrstype.RrsType{rrstype.A, rrstype.AAAA} {
+				addresses := byType[recordType]
+				if len(addresses) == 0 {
+					continue
+				}
+				bind := endpoint
+				bind.Targets = addresses
+				target := routing.HealthCheckTarget{Address: addresses[0], Port: c.healthCheckPort}
+				id, ok := ids[target]
+				if !ok {
+					if !create {
+						c.log.Infof("Health check of %s:%d not found, it would be created", target.Address, target.Port)
+						result = append(result, bind)
+						continue
+					}
+					c.log.Infof("Creating health check of %s:%d", target.Address, target.Port)
+					id, err = checker.AddHealthCheck(c.ownerID, target)
+					if err != nil {
+						// The health check might have been created nonetheless.
+						c.healthChecks = nil
+						return err
+					}
+					ids[target] = id
+				}
+				bind.Policy.HealthCheckID = id
+				bound[id] = true
+				result = append(result, bind)
+			}
+		}
+		endpoints[z] = result
+	}
+	c.boundHealthChecks = bound
+	return nil
+}
+
+// listHealthChecks returns the owned health checks by their targets. They are
+// listed from the DNS Provider together with the Records of the zones, see
+// DriftCheckInterval, and kept up to date with the health checks created and
+// removed by the Controller in between.
+func (c *Controller) listHealthChecks() (map[routing.HealthCheckTarget]string, error) {
+	if c.healthChecks != nil && !c.needsZoneList() {
+		return c.healthChecks, nil
+	}
+	ids, err := c.dns.(routing.HealthChecker).HealthChecks(c.ownerID)
+	if err != nil {
+		return nil, err
+	}
+	providerLists.WithLabelValues("health_checks").Inc()
+	c.healthChecks = ids
+	return ids, nil
+}
+
+// removeStaleHealthChecks removes the owned health checks that were not bound
+// to a Record by the last call of bindHealthChecks.
+func (c *Controller) removeStaleHealthChecks() error {
+	if c.healthCheckPort == 0 || c.boundHealthChecks == nil || c.healthChecks == nil {
+		return nil
+	}
+	checker := c.dns.(routing.HealthChecker)
+	for target, id := range c.healthChecks {
+		if c.boundHealthChecks[id] {
+			continue
+		}
+		c.log.Infof("Removing stale health check of %s:%d", target.Address, target.Port)
+		if err := checker.RemoveHealthCheck(id); err != nil {
+			c.healthChecks = nil
+			return err
+		}
+		delete(c.healthChecks, target)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/apis/extensions"
+)
+
+// ingressSource is a Source providing Endpoints for the hosts of Ingresses
+// that are inside of the zone.
+type ingressSource struct {
+	log         *logrus.Logger
+	zoneName    string
+	nodes       *nodeSource
+	addressType api.NodeAddressType
+	ingresses   *watcher
+}
+
+// newIngressSource creates an ingressSource for the Ingresses of watcher ingresses. Ingresses
+// without load balancer IPs point to the addresses of addressType of the Ready Nodes of nodes.
+func newIngressSource(ingresses *watcher, log *logrus.Logger, zoneName string, nodes *nodeSource, addressType api.NodeAddressType) *ingressSource {
+	return &ingressSource{log: log, zoneName: zoneName, nodes: nodes, addressType: addressType, ingresses: ingresses}
+}
+
+// Changes implements Source.
+func (s *ingressSource) Changes() <-chan struct{} {
+	return s.ingresses.Changes()
+}
+
+// Endpoints implements Source.
+func (s *ingressSource) Endpoints() ([]Endpoint, error) {
+	endpoints := []Endpoint{}
+	for _, x := range s.ingresses.List() {
+		ingress := x.(*extensions.Ingress)
+		hosts := s.ingressHosts(ingress)
+		if len(hosts) == 0 {
+			continue
+		}
+		addresses, err := s.ingressAddresses(ingress)
+		if err != nil {
+			s.log.Warnf("Skipping Ingress %s/%s: %v", ingress.Namespace, ingress.Name, err)
+			continue
+		}
+		for _, name := range hosts {
+			endpoints = append(endpoints, Endpoint{DNSName: name, Targets: addresses})
+		}
+	}
+	return endpoints, nil
+}
+
+// ingressHosts returns the hosts of ingress that are inside of the zone as record names.
+func (s *ingressSource) ingressHosts(ingress *extensions.Ingress) []string {
+	var names []string
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host == "" {
+			continue
+		}
+		name := strings.ToLower(rule.Host)
+		if !strings.HasSuffix(name, ".") {
+			name += "."
+		}
+		if validateRecordName(name, s.zoneName) != nil {
+			continue
+		}
+		names = appendUnique(names, name)
+	}
+	return names
+}
+
+// ingressAddresses returns the addresses of the load balancer of ingress or, when
+// it has no load balancer, the addresses of the Ready Nodes.
+func (s *ingressSource) ingressAddresses(ingress *extensions.Ingress) ([]string, error) {
+	if len(ingress.Status.LoadBalancer.Ingress) > 0 {
+		return loadBalancerIPs(ingress.Status.LoadBalancer)
+	}
+	return s.nodes.ReadyAddresses(s.addressType), nil
+}
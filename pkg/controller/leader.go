@@ -0,0 +1,199 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/client/leaderelection"
+	"k8s.io/kubernetes/pkg/client/unversioned"
+)
+
+// LeaderElectionOptions configure the election of a leader between replicas of
+// the Controller. Only the leader syncs to DNS, the others keep watching the
+// Kubernetes API to take over quickly.
+type LeaderElectionOptions struct {
+	// Namespace of the Endpoints object used as lock, defaults to "default".
+	Namespace string
+
+	// Name of the Endpoints object used as lock, defaults to "kube-dns-sync".
+	Name string
+
+	// Identity of this replica, defaults to the hostname.
+	Identity string
+
+	// LeaseDuration is the time standbys wait after the last renewal of the
+	// leader before taking over, defaults to 15 seconds.
+	LeaseDuration time.Duration
+
+	// RenewDeadline is the time the leader retries to renew its lease before
+	// giving up leadership, defaults to 10 seconds.
+	RenewDeadline time.Duration
+
+	// RetryPeriod is the interval of acquiring and renewing the lease, defaults to 2 seconds.
+	RetryPeriod time.Duration
+}
+
+// newElector creates the leader elector of the Controller, which runs the sync
+// loop while leading.
+func (c *Controller) newElector(opts LeaderElectionOptions) (*leaderelection.LeaderElector, error) {
+	if opts.Namespace == "" {
+		opts.Namespace = api.NamespaceDefault
+	}
+	if opts.Name == "" {
+		opts.Name = "kube-dns-sync"
+	}
+	if opts.Identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine leader election identity: %v", err)
+		}
+		opts.Identity = hostname
+	}
+	if opts.LeaseDuration == 0 {
+		opts.LeaseDuration = 15 * time.Second
+	}
+	if opts.RenewDeadline == 0 {
+		opts.RenewDeadline = 10 * time.Second
+	}
+	if opts.RetryPeriod == 0 {
+		opts.RetryPeriod = 2 * time.Second
+	}
+	recorder := c.recorder
+	if recorder == nil {
+		// The leader transitions are only sent to the Kubernetes API when Events are enabled.
+		_, recorder = newEventRecorder()
+	}
+	c.identity = opts.Identity
+	return leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		EndpointsMeta: api.ObjectMeta{Namespace: opts.Namespace, Name: opts.Name},
+		Identity:      opts.Identity,
+		Client:        &electionClient{Interface: c.client, stopCh: c.stopCh},
+		EventRecorder: recorder,
+		LeaseDuration: opts.LeaseDuration,
+		RenewDeadline: opts.RenewDeadline,
+		RetryPeriod:   opts.RetryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: c.lead,
+			OnStoppedLeading: func() {
+				c.log.Warnf("Lost leadership as %q", c.identity)
+			},
+		},
+	})
+}
+
+// runElected campaigns for leadership until stopCh is closed. The elector
+// runs the sync loop while leading and campaigns again after losing the lease.
+func (c *Controller) runElected(stopCh <-chan struct{}) {
+	go func() {
+		for {
+			c.log.Infof("Waiting to become leader as %q", c.identity)
+			c.elector.Run()
+			select {
+			case <-stopCh:
+				return
+			default:
+			}
+		}
+	}()
+	<-stopCh
+}
+
+// lead runs the sync loop until leaderStopCh is closed because the lease could
+// not be renewed, or until the Controller is stopped.
+func (c *Controller) lead(leaderStopCh <-chan struct{}) {
+	c.log.Infof("Became leader as %q", c.identity)
+	// Another leader might have changed the Records in the meantime.
+	c.invalidateCaches()
+	c.setLeading(true)
+
+	loopStopCh := make(chan struct{})
+	go func() {
+		select {
+		case <-leaderStopCh:
+		case <-c.stopCh:
+		}
+		// Stop applying changes of a running sync right away.
+		c.setLeading(false)
+		close(loopStopCh)
+	}()
+	if !c.waitForWatchers(loopStopCh) {
+		return
+	}
+	c.loop(loopStopCh)
+}
+
+// setLeading records whether this replica is the leader.
+func (c *Controller) setLeading(leading bool) {
+	c.health.setLeading(leading)
+	if leading {
+		leaderGauge.Set(1)
+	} else {
+		leaderGauge.Set(0)
+	}
+}
+
+// IsLeader returns true when the Controller is syncing to DNS, which is always
+// the case when leader election is disabled.
+func (c *Controller) IsLeader() bool {
+	if c.elector == nil {
+		return true
+	}
+	c.health.mu.Lock()
+	defer c.health.mu.Unlock()
+	return c.health.leading
+}
+
+// electionClient fails the requests of the elector once stopCh is closed, so that
+// a stopped Controller stops renewing its lease and a standby takes over.
+type electionClient struct {
+	unversioned.Interface
+	stopCh <-chan struct{}
+}
+
+func (c *electionClient) Endpoints(namespace string) unversioned.EndpointsInterface {
+	return &electionEndpoints{EndpointsInterface: c.Interface.Endpoints(namespace), stopCh: c.stopCh}
+}
+
+type electionEndpoints struct {
+	unversioned.EndpointsInterface
+	stopCh <-chan struct{}
+}
+
+func (e *electionEndpoints) stopped() error {
+	select {
+	case <-e.stopCh:
+		return fmt.Errorf("controller stopped")
+	default:
+		return nil
+	}
+}
+
+func (e *electionEndpoints) Get(name string) (*api.Endpoints, error) {
+	if err := e.stopped(); err != nil {
+		return nil, err
+	}
+	return e.EndpointsInterface.Get(name)
+}
+
+func (e *electionEndpoints) Create(endpoints *api.Endpoints) (*api.Endpoints, error) {
+	if err := e.stopped(); err != nil {
+		return nil, err
+	}
+	return e.EndpointsInterface.Create(endpoints)
+}
+
+func (e *electionEndpoints) Update(endpoints *api.Endpoints) (*api.Endpoints, error) {
+	if err := e.stopped(); err != nil {
+		return nil, err
+	}
+	return e.EndpointsInterface.Update(endpoints)
+}
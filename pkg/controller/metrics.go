@@ -0,0 +1,160 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	utilerrors "k8s.io/kubernetes/pkg/util/errors"
+)
+
+const metricsNamespace = "kube_dns_sync"
+
+// Kinds of sync errors used as label of the failure counter.
+const (
+	errorKindProvider     = "provider"
+	errorKindUnsupported  = "unsupported"
+	errorKindSource       = "source"
+	errorKindZoneNotFound = "zone_not_found"
+	errorKindZoneCreation = "zone_creation"
+	errorKindApply        = "apply"
+	errorKindUnknown      = "unknown"
+)
+
+var (
+	syncDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "sync_duration_seconds",
+		Help:      "Duration of syncs with the DNS Provider.",
+	})
+	syncSuccesses = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "sync_successes_total",
+		Help:      "Number of syncs that succeeded for all zones.",
+	})
+	syncFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "sync_failures_total",
+		Help:      "Number of errors of failed syncs by kind, a sync fails with one error per failed zone.",
+	}, []string{"kind"})
+	lastSuccessfulSync = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "last_successful_sync_timestamp_seconds",
+		Help:      "Unix timestamp of the last sync that succeeded for all zones.",
+	})
+	recordChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "record_changes_total",
+		Help:      "Number of Records added, removed and updated.",
+	}, []string{"zone", "action"})
+	providerLists = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "provider_lists_total",
+		Help:      "Number of listings of zones and Records from the DNS Provider.",
+	}, []string{"resource"})
+	recordsUnchanged = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "records_unchanged",
+		Help:      "Number of managed Records that were up to date in the last sync.",
+	}, []string{"zone"})
+	nodeCount = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "nodes",
+		Help:      "Number of selected Nodes with addresses of an address type by condition: ready, not_ready or ineligible.",
+	}, []string{"zone", "address_type", "condition"})
+	consecutiveFailures = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "consecutive_sync_failures",
+		Help:      "Number of consecutive syncs failed with retryable errors, the circuit breaker opens at the failure threshold.",
+	})
+	coalescedEvents = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "coalesced_events_total",
+		Help:      "Number of changes that were merged into an already pending sync request.",
+	})
+	leaderGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "leader",
+		Help:      "1 when this replica is the elected leader syncing to DNS, 0 otherwise.",
+	})
+	informerEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "informer_events_total",
+		Help:      "Number of events received from the Kubernetes API.",
+	}, []string{"resource", "event"})
+)
+
+func init() {
+	prometheus.MustRegister(syncDuration)
+	prometheus.MustRegister(syncSuccesses)
+	prometheus.MustRegister(syncFailures)
+	prometheus.MustRegister(lastSuccessfulSync)
+	prometheus.MustRegister(recordChanges)
+	prometheus.MustRegister(providerLists)
+	prometheus.MustRegister(recordsUnchanged)
+	prometheus.MustRegister(nodeCount)
+	prometheus.MustRegister(consecutiveFailures)
+	prometheus.MustRegister(coalescedEvents)
+	prometheus.MustRegister(leaderGauge)
+	prometheus.MustRegister(informerEvents)
+}
+
+// syncError is an error of a sync classified by its kind. It keeps the original
+// error of the DNS Provider, so that it can be classified by isRetryable.
+type syncError struct {
+	kind string
+	// context describes what failed, like "failed to create Zone", and prefixes the message.
+	context string
+	err     error
+}
+
+// Error implements error.
+func (e *syncError) Error() string {
+	if e.context == "" {
+		return e.err.Error()
+	}
+	return e.context + ": " + e.err.Error()
+}
+
+// newSyncError returns err classified as kind.
+func newSyncError(kind string, err error) error {
+	return &syncError{kind: kind, err: err}
+}
+
+// newSyncErrorf returns err classified as kind with the context described by format.
+func newSyncErrorf(kind string, err error, format string, args ...interface{}) error {
+	return &syncError{kind: kind, context: fmt.Sprintf(format, args...), err: err}
+}
+
+// errorKind returns the kind of err.
+func errorKind(err error) string {
+	if e, ok := err.(*syncError); ok {
+		return e.kind
+	}
+	return errorKindUnknown
+}
+
+// observeSync records the metrics of a sync that started at start and returned err.
+func observeSync(start time.Time, err error) {
+	syncDuration.Observe(time.Since(start).Seconds())
+	if err == nil {
+		syncSuccesses.Inc()
+		lastSuccessfulSync.Set(float64(time.Now().Unix()))
+		return
+	}
+	errs := []error{err}
+	if agg, ok := err.(utilerrors.Aggregate); ok {
+		errs = agg.Errors()
+	}
+	for _, x := range errs {
+		syncFailures.WithLabelValues(errorKind(x)).Inc()
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"k8s.io/kubernetes/pkg/api"
+)
+
+// DefaultRecordNameTemplate results in names like "externalip.example.com.".
+const DefaultRecordNameTemplate = "{{.AddressType}}.{{.Zone}}"
+
+// recordNameData is passed to the record name template.
+type recordNameData struct {
+	// AddressType in lower case, like "externalip".
+	AddressType string
+	// NodeName is the name of the Node.
+	NodeName string
+	// Labels of the Node.
+	Labels map[string]string
+	// Zone name, like "example.com.".
+	Zone string
+}
+
+// parseRecordNameTemplate parses text as a record name template.
+func parseRecordNameTemplate(text string) (*template.Template, error) {
+	return template.New("record-name").Option("missingkey=zero").Parse(text)
+}
+
+// recordName renders the record name for addressType of node.
+func (s *nodeSource) recordName(addressType api.NodeAddressType, node *api.Node) (string, error) {
+	var buf bytes.Buffer
+	err := s.recordNameTemplate.Execute(&buf, &recordNameData{
+		AddressType: strings.ToLower(string(addressType)),
+		NodeName:    node.Name,
+		Labels:      node.Labels,
+		Zone:        s.zoneName,
+	})
+	if err != nil {
+		return "", err
+	}
+	name := strings.ToLower(buf.String())
+	if err := validateRecordName(name, s.zoneName); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// groupLabel converts the value of the group label of a Node into a DNS label,
+// like "pool-a" for "Pool_A". It returns an empty string when nothing is left.
+func groupLabel(value string) string {
+	label := []byte(strings.ToLower(value))
+	for i, x := range label {
+		if (x < 'a' || x > 'z') && (x < '0' || x > '9') {
+			label[i] = '-'
+		}
+	}
+	return strings.Trim(string(label), "-")
+}
+
+// validateRecordName returns an error if name is not a valid name inside of zone.
+func validateRecordName(name, zone string) error {
+	if name != zone && !strings.HasSuffix(name, "."+zone) {
+		return fmt.Errorf("record name %q is not inside of zone %q", name, zone)
+	}
+	if strings.HasPrefix(name, ".") || strings.Contains(name, "..") {
+		return fmt.Errorf("record name %q contains an empty label", name)
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/pkg/labels"
+
+	k8sutil "github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes"
+)
+
+// nodeRecordOptions configure the Endpoints created from Nodes.
+type nodeRecordOptions struct {
+	zoneName           string
+	addressTypes       []api.NodeAddressType
+	apexAddressType    api.NodeAddressType
+	selector           labels.Selector
+	recordNameTemplate *template.Template
+	nodeRecords        bool
+	groupByLabel       string
+	topologyRecords    bool
+	latencyRouting     bool
+	weightedRecords    bool
+	eligibility        NodeEligibility
+}
+
+// nodeSource is a Source providing Endpoints for the addresses of the Ready Nodes.
+type nodeSource struct {
+	nodeRecordOptions
+	log   *logrus.Logger
+	nodes *watcher
+}
+
+// newNodeSource creates a nodeSource for the Nodes of watcher nodes.
+func newNodeSource(nodes *watcher, log *logrus.Logger, opts nodeRecordOptions) *nodeSource {
+	return &nodeSource{nodeRecordOptions: opts, log: log, nodes: nodes}
+}
+
+// Changes implements Source.
+func (s *nodeSource) Changes() <-chan struct{} {
+	return s.nodes.Changes()
+}
+
+// Nodes returns all watched Nodes matching the selector.
+func (s *nodeSource) Nodes() []*api.Node {
+	var nodes []*api.Node
+	for _, x := range s.nodes.List() {
+		node := x.(*api.Node)
+		if s.selector != nil && !s.selector.Matches(labels.Set(node.Labels)) {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// ReadyAddresses returns the addresses of addressType of all Ready and eligible Nodes.
+func (s *nodeSource) ReadyAddresses(addressType api.NodeAddressType) []string {
+	var addresses []string
+	for _, node := range s.Nodes() {
+		if k8sutil.IsNodeReady(node) && s.eligibility.ineligibility(node, s.log) == "" {
+			addresses = append(addresses, s.nodeAddresses(node, addressType)...)
+		}
+	}
+	return addresses
+}
+
+// Endpoints implements Source.
+func (s *nodeSource) Endpoints() ([]Endpoint, error) {
+	addressTypes := s.addressTypes
+	apexInGroup := false
+	if s.apexAddressType != "" {
+		for _, x := range s.addressTypes {
+			if s.apexAddressType == x {
+				apexInGroup = true
+				break
+			}
+		}
+		if !apexInGroup {
+			addressTypes = append(addressTypes, s.apexAddressType)
+		}
+	}
+
+	nodes := s.Nodes()
+	ineligibilities := map[*api.Node]string{}
+	for _, node := range nodes {
+		if reason := s.eligibility.ineligibility(node, s.log); reason != "" && k8sutil.IsNodeReady(node) {
+			s.log.Debugf("Excluding Node %q: %s", node.Name, reason)
+			ineligibilities[node] = reason
+		}
+	}
+	endpoints := []Endpoint{}
+	for _, addressType := range addressTypes {
+		ready, notReady, ineligible := 0, 0, 0
+		for _, node := range nodes {
+			addresses := s.nodeAddresses(node, addressType)
+			if len(addresses) == 0 {
+				continue
+			}
+			if !k8sutil.IsNodeReady(node) {
+				notReady++
+				continue
+			}
+			if ineligibilities[node] != "" {
+				ineligible++
+				continue
+			}
+			ready++
+			var names []string
+			if addressType == s.apexAddressType {
+				names = append(names, s.zoneName)
+			}
+			if addressType != s.apexAddressType || apexInGroup {
+				name, err := s.recordName(addressType, node)
+				if err != nil {
+					s.log.Warnf("Skipping Node %q: %v", node.Name, err)
+				} else {
+					names = append(names, name)
+				}
+			}
+			group := s.group(node)
+			topology := s.topologyLabels(node)
+			policy, routable := s.routingPolicy(node)
+			if !routable {
+				s.log.Warnf("Skipping Node %q in latency routed Records: missing label %s", node.Name, unversioned.LabelZoneRegion)
+			}
+			for _, name := range names {
+				if routable {
+					endpoints = append(endpoints, Endpoint{DNSName: name, Targets: addresses, Policy: policy})
+				}
+				if group != "" {
+					endpoints = append(endpoints, Endpoint{DNSName: group + "." + name, Targets: addresses})
+				}
+				for _, label := range topology {
+					endpoints = append(endpoints, Endpoint{DNSName: label + "." + name, Targets: addresses})
+				}
+				if s.nodeRecords {
+					nodeName := strings.ToLower(node.Name) + "." + name
+					endpoints = append(endpoints, Endpoint{DNSName: nodeName, Targets: addresses})
+				}
+			}
+		}
+		nodeCount.WithLabelValues(s.zoneName, strings.ToLower(string(addressType)), "ready").Set(float64(ready))
+		nodeCount.WithLabelValues(s.zoneName, strings.ToLower(string(addressType)), "not_ready").Set(float64(notReady))
+		nodeCount.WithLabelValues(s.zoneName, strings.ToLower(string(addressType)), "ineligible").Set(float64(ineligible))
+	}
+	for _, node := range nodes {
+		if k8sutil.IsNodeReady(node) && ineligibilities[node] == "" {
+			endpoints = append(endpoints, s.extraNameEndpoints(node)...)
+		}
+	}
+	return endpoints, nil
+}
+
+// group returns the DNS label of the group of node by the value of the label
+// groupByLabel, or an empty string when node is not in a group.
+func (s *nodeSource) group(node *api.Node) string {
+	if s.groupByLabel == "" {
+		return ""
+	}
+	value, ok := node.Labels[s.groupByLabel]
+	if !ok {
+		return ""
+	}
+	group := groupLabel(value)
+	if group == "" {
+		s.log.Warnf("Skipping group of Node %q: label %s=%q is not a valid DNS label", node.Name, s.groupByLabel, value)
+	}
+	return group
+}
+
+// extraNameEndpoints returns the Endpoints of the names of AnnotationExtraNames
+// of node that are inside of the zone.
+func (s *nodeSource) extraNameEndpoints(node *api.Node) []Endpoint {
+	names, err := extraNames(node)
+	if err != nil {
+		s.log.Warnf("Ignoring %s of Node %q: %v", AnnotationExtraNames, node.Name, err)
+		return nil
+	}
+	var endpoints []Endpoint
+	for addressType, list := range names {
+		addresses := s.nodeAddresses(node, addressType)
+		if len(addresses) == 0 {
+			continue
+		}
+		for _, name := range list {
+			if validateRecordName(name, s.zoneName) != nil {
+				continue
+			}
+			endpoints = append(endpoints, Endpoint{DNSName: name, Targets: addresses, Annotated: true})
+		}
+	}
+	return endpoints
+}
+
+// nodeAddresses returns the addresses of addressType of node, which are replaced
+// by the addresses of AnnotationAddressOverride when present.
+func (s *nodeSource) nodeAddresses(node *api.Node, addressType api.NodeAddressType) []string {
+	overrides, err := addressOverrides(node)
+	if err != nil {
+		s.log.Warnf("Ignoring %s of Node %q: %v", AnnotationAddressOverride, node.Name, err)
+	} else if addresses, ok := overrides[addressType]; ok {
+		return addresses
+	}
+	return statusAddresses(node, addressType)
+}
+
+// statusAddresses returns the addresses of addressType in the status of node.
+func statusAddresses(node *api.Node, addressType api.NodeAddressType) []string {
+	var addresses []string
+	for _, x := range node.Status.Addresses {
+		if x.Type == addressType {
+			addresses = append(addresses, x.Address)
+		}
+	}
+	return addresses
+}
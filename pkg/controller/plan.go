@@ -0,0 +1,111 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"fmt"
+
+	"k8s.io/kubernetes/federation/pkg/dnsprovider"
+
+	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/routing"
+)
+
+// Action is the kind of a Change.
+type Action string
+
+const (
+	// ActionAdd adds a new Record.
+	ActionAdd Action = "add"
+	// ActionRemove removes an existing Record.
+	ActionRemove Action = "remove"
+	// ActionUpdate replaces an existing Record that diverged.
+	ActionUpdate Action = "update"
+)
+
+// Change of a single Record computed by a sync.
+type Change struct {
+	Action  Action   `json:"action"`
+	Zone    string   `json:"zone"`
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	TTL     int64    `json:"ttl"`
+	Rrdatas []string `json:"rrdatas"`
+
+	// SetIdentifier distinguishes Records of a routing policy sharing name and type.
+	SetIdentifier string `json:"setIdentifier,omitempty"`
+
+	// OldTTL and OldRrdatas are the values replaced by an update.
+	OldTTL     int64    `json:"oldTTL,omitempty"`
+	OldRrdatas []string `json:"oldRrdatas,omitempty"`
+
+	// record is added, removed or the replacement of old.
+	record dnsprovider.ResourceRecordSet
+	old    dnsprovider.ResourceRecordSet
+}
+
+// String returns a human-readable description of the Change.
+func (c Change) String() string {
+	name := fmt.Sprintf("%q", c.Name)
+	if c.SetIdentifier != "" {
+		name = fmt.Sprintf("%q (%s)", c.Name, c.SetIdentifier)
+	}
+	if c.Action == ActionUpdate {
+		return fmt.Sprintf("%s %s Record %s: ttl %d -> %d, %v -> %v", c.Action, c.Type, name, c.OldTTL, c.TTL, c.OldRrdatas, c.Rrdatas)
+	}
+	return fmt.Sprintf("%s %s Record %s: ttl %d, %v", c.Action, c.Type, name, c.TTL, c.Rrdatas)
+}
+
+// newChange creates a Change adding or removing record in zone.
+func newChange(action Action, zone string, record dnsprovider.ResourceRecordSet) Change {
+	return Change{
+		Action:  action,
+		Zone:    zone,
+		Name:    record.Name(),
+		Type:    string(record.Type()),
+		TTL:     record.Ttl(),
+		Rrdatas: record.Rrdatas(),
+		record:  record,
+
+		SetIdentifier: routing.PolicyOf(record).SetIdentifier,
+	}
+}
+
+// newUpdateChange creates a Change replacing old with record in zone.
+func newUpdateChange(zone string, old, record dnsprovider.ResourceRecordSet) Change {
+	change := newChange(ActionUpdate, zone, record)
+	change.OldTTL = old.Ttl()
+	change.OldRrdatas = old.Rrdatas()
+	change.old = old
+	return change
+}
+
+// zonePlan holds the Changes computed for a zone.
+type zonePlan struct {
+	zone    *zone
+	rrs     dnsprovider.ResourceRecordSets
+	changes []Change
+	// unchanged is the number of managed Records that are up to date.
+	unchanged int
+}
+
+// Plan computes the Changes that a sync would apply without applying them.
+// It starts watching the Kubernetes API and waits for the initial listing,
+// so use either Run or Plan on a Controller.
+func (c *Controller) Plan() ([]Change, error) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	c.startWatchers(stopCh)
+	c.waitForWatchers(stopCh)
+
+	plans, err := c.plan(false)
+	changes := []Change{}
+	for _, p := range plans {
+		changes = append(changes, p.changes...)
+	}
+	return changes, err
+}
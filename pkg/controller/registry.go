@@ -0,0 +1,106 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/kubernetes/federation/pkg/dnsprovider"
+	"k8s.io/kubernetes/federation/pkg/dnsprovider/rrstype"
+)
+
+// heritage identifies TXT Records written by kube-dns-sync.
+const heritage = "kube-dns-sync"
+
+// ownershipPrefix is prepended to a name to get the name of its ownership Record.
+// The ownership Record must not share the TXT Resource Record Set of the name
+// itself, which often holds SPF or site verification Records.
+const ownershipPrefix = "_kube-dns-sync."
+
+// txtType is the Resource Record Set type used for ownership Records.
+const txtType = rrstype.RrsType("TXT")
+
+// ownership describes who owns the Records of a given name.
+type ownership int
+
+const (
+	// unclaimed names have neither address nor ownership Records.
+	unclaimed ownership = iota
+	// owned names have an ownership Record with our owner id.
+	owned
+	// foreign names have an ownership Record of another owner.
+	foreign
+	// unmarked names have address Records but no ownership Record, e.g. Records
+	// created by hand or by versions without ownership. See AdoptExisting.
+	unmarked
+	// occupied names hold a CNAME Record, which can't coexist with address or
+	// ownership Records. Other types, like TXT or MX Records, can.
+	occupied
+)
+
+// ownershipName returns the name of the ownership Record of name.
+func ownershipName(name string) string {
+	return ownershipPrefix + name
+}
+
+// ownershipLabel returns the TXT data that marks a name as owned by this Controller.
+func (c *Controller) ownershipLabel() string {
+	return fmt.Sprintf("\"heritage=%s,owner=%s\"", heritage, c.ownerID)
+}
+
+// newOwnershipRecord creates the TXT Record that marks name as owned by this Controller.
+func (c *Controller) newOwnershipRecord(rrs dnsprovider.ResourceRecordSets, name string, ttl int64) dnsprovider.ResourceRecordSet {
+	return rrs.New(ownershipName(name), []string{c.ownershipLabel()}, ttl, txtType)
+}
+
+// isOwnershipRecord returns true when record is an ownership Record carrying our ownership label.
+func (c *Controller) isOwnershipRecord(record dnsprovider.ResourceRecordSet) bool {
+	if record.Type() != txtType || !strings.HasPrefix(record.Name(), ownershipPrefix) {
+		return false
+	}
+	for _, x := range record.Rrdatas() {
+		if x == c.ownershipLabel() {
+			return true
+		}
+	}
+	return false
+}
+
+// recordOwnership determines the ownership of all names in recordList.
+// Names not in the returned map are unclaimed. TXT Records at the names
+// themselves, like SPF Records, don't claim a name, while CNAME Records
+// occupy it regardless of its ownership Record.
+func (c *Controller) recordOwnership(recordList []dnsprovider.ResourceRecordSet) map[string]ownership {
+	result := map[string]ownership{}
+	for _, x := range recordList {
+		if x.Type() != txtType || !strings.HasPrefix(x.Name(), ownershipPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(x.Name(), ownershipPrefix)
+		if c.isOwnershipRecord(x) {
+			result[name] = owned
+		} else if result[name] != owned {
+			result[name] = foreign
+		}
+	}
+	for _, x := range recordList {
+		if x.Type() != rrstype.A && x.Type() != rrstype.AAAA {
+			continue
+		}
+		if _, ok := result[x.Name()]; !ok {
+			result[x.Name()] = unmarked
+		}
+	}
+	for _, x := range recordList {
+		if x.Type() == rrstype.CNAME {
+			result[x.Name()] = occupied
+		}
+	}
+	return result
+}
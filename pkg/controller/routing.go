@@ -0,0 +1,42 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+
+	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/routing"
+)
+
+// DefaultWeight is the weight of the weighted Records of Nodes without AnnotationWeight.
+const DefaultWeight = 1
+
+// maxWeight is the maximum weight of a weighted Record.
+const maxWeight = 255
+
+// routingPolicy returns the routing policy of the address type Records of node.
+// ok is false when latency routing is enabled but node has no region.
+func (s *nodeSource) routingPolicy(node *api.Node) (policy routing.Policy, ok bool) {
+	switch {
+	case s.weightedRecords:
+		weight, err := nodeWeight(node)
+		if err != nil {
+			s.log.Warnf("Ignoring %s of Node %q: %v", AnnotationWeight, node.Name, err)
+			weight = DefaultWeight
+		}
+		return routing.Policy{SetIdentifier: node.Name, Weighted: true, Weight: weight}, true
+	case s.latencyRouting:
+		region := node.Labels[unversioned.LabelZoneRegion]
+		if region == "" {
+			return routing.Policy{}, false
+		}
+		return routing.Policy{SetIdentifier: region, Region: region}, true
+	}
+	return routing.Policy{}, true
+}
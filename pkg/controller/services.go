@@ -0,0 +1,102 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/kubernetes/pkg/api"
+)
+
+// AnnotationPublish opts a Service in to be synced to DNS when set to "true".
+const AnnotationPublish = "kube-dns-sync/publish"
+
+// isServicePublished returns true when service opted in to be synced to DNS.
+func isServicePublished(service *api.Service) bool {
+	return service.Annotations[AnnotationPublish] == "true"
+}
+
+// serviceSource is a Source providing Endpoints like "<service>.<namespace>.example.com."
+// for published Services.
+type serviceSource struct {
+	log         *logrus.Logger
+	zoneName    string
+	nodes       *nodeSource
+	addressType api.NodeAddressType
+	services    *watcher
+}
+
+// newServiceSource creates a serviceSource for the Services of watcher services. NodePort
+// Services point to the addresses of addressType of the Ready Nodes of nodes.
+func newServiceSource(services *watcher, log *logrus.Logger, zoneName string, nodes *nodeSource, addressType api.NodeAddressType) *serviceSource {
+	return &serviceSource{log: log, zoneName: zoneName, nodes: nodes, addressType: addressType, services: services}
+}
+
+// Changes implements Source.
+func (s *serviceSource) Changes() <-chan struct{} {
+	return s.services.Changes()
+}
+
+// Endpoints implements Source.
+func (s *serviceSource) Endpoints() ([]Endpoint, error) {
+	endpoints := []Endpoint{}
+	for _, x := range s.services.List() {
+		service := x.(*api.Service)
+		if !isServicePublished(service) {
+			continue
+		}
+		endpoint, err := s.serviceEndpoint(service)
+		if err != nil {
+			s.log.Warnf("Skipping Service %s/%s: %v", service.Namespace, service.Name, err)
+			continue
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
+
+// serviceEndpoint returns the Endpoint for service. LoadBalancer Services point to their
+// ingress IPs and NodePort Services to the addresses of the Ready Nodes.
+func (s *serviceSource) serviceEndpoint(service *api.Service) (Endpoint, error) {
+	name := strings.ToLower(service.Name + "." + service.Namespace + "." + s.zoneName)
+	if err := validateRecordName(name, s.zoneName); err != nil {
+		return Endpoint{}, err
+	}
+	switch service.Spec.Type {
+	case api.ServiceTypeLoadBalancer:
+		addresses, err := loadBalancerIPs(service.Status.LoadBalancer)
+		if err != nil {
+			return Endpoint{}, err
+		}
+		return Endpoint{DNSName: name, Targets: addresses}, nil
+	case api.ServiceTypeNodePort:
+		return Endpoint{DNSName: name, Targets: s.nodes.ReadyAddresses(s.addressType)}, nil
+	}
+	return Endpoint{}, fmt.Errorf("Service of type %q can't be published", service.Spec.Type)
+}
+
+// loadBalancerIPs returns the ingress IPs of status. Load balancers that only have
+// hostnames, like the ELBs of AWS, are not supported as CNAME Records are not synced.
+func loadBalancerIPs(status api.LoadBalancerStatus) ([]string, error) {
+	var addresses []string
+	var hostname string
+	for _, x := range status.Ingress {
+		if x.IP != "" {
+			addresses = append(addresses, x.IP)
+		} else if x.Hostname != "" && hostname == "" {
+			hostname = x.Hostname
+		}
+	}
+	if len(addresses) == 0 && hostname != "" {
+		return nil, fmt.Errorf("load balancer %q has no IPs, hostnames are not supported", hostname)
+	}
+	return addresses, nil
+}
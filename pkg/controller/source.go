@@ -0,0 +1,69 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import "github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/routing"
+
+// Endpoint is a desired DNS name and the addresses it points to.
+type Endpoint struct {
+	// DNSName is the fully qualified name, like "externalip.example.com.".
+	DNSName string
+
+	// Targets are the IP addresses of the Endpoint.
+	Targets []string
+
+	// Policy is the routing policy of the Record, Endpoints with different
+	// set identifiers are synced to separate Records.
+	Policy routing.Policy
+
+	// Annotated marks Endpoints requested by annotations, like AnnotationExtraNames.
+	// They are merged with each other, but dropped when an Endpoint that is not
+	// annotated has the same DNSName, so that annotations can't take over names.
+	Annotated bool
+}
+
+// Source provides the desired Endpoints. Endpoints of all Sources with the same
+// DNSName are merged into one Record.
+type Source interface {
+	// Endpoints returns the current list of desired Endpoints.
+	Endpoints() ([]Endpoint, error)
+
+	// Changes returns a channel that receives a value whenever the Endpoints might have changed.
+	Changes() <-chan struct{}
+}
+
+// Runner is implemented by Sources that need to be started by the Controller.
+type Runner interface {
+	// Run starts the Source and returns immediately. The Source must stop when stopCh is closed.
+	Run(stopCh <-chan struct{})
+}
+
+// notifier implements the change notification of a Source.
+type notifier struct {
+	ch chan struct{}
+}
+
+// newNotifier creates a new notifier.
+func newNotifier() notifier {
+	return notifier{ch: make(chan struct{}, 1)}
+}
+
+// notify signals a change without blocking. A change is coalesced with a
+// pending one that was not received yet.
+func (n notifier) notify() {
+	select {
+	case n.ch <- struct{}{}:
+	default:
+		coalescedEvents.Inc()
+	}
+}
+
+// Changes implements Source.
+func (n notifier) Changes() <-chan struct{} {
+	return n.ch
+}
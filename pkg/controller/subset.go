@@ -0,0 +1,90 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"hash/fnv"
+	"sort"
+)
+
+// addressOwners returns the names of the Nodes by their addresses, which identify
+// the addresses for selectAddresses. An address shared by several Nodes belongs
+// to the lowest name, independent of the order of the watch cache. Nil when
+// MaxAddressesPerRecord is not set.
+func (c *Controller) addressOwners() map[string]string {
+	if c.maxAddresses == 0 {
+		return nil
+	}
+	result := map[string]string{}
+	for address, nodes := range c.nodesByAddress() {
+		for _, node := range nodes {
+			if owner, ok := result[address]; !ok || node.Name < owner {
+				result[address] = node.Name
+			}
+		}
+	}
+	return result
+}
+
+// selectAddresses returns at most max of the addresses of the Record name using
+// rendezvous hashing: each address is ranked by the hash of name and the Node
+// owning the address, or the address itself when no Node owns it. The selection
+// only changes when a selected Node goes away or a Node ranking higher appears,
+// and differs between Records so that all Nodes get a share of the traffic.
+func selectAddresses(name string, addresses []string, owners map[string]string, max int) []string {
+	if max <= 0 || len(addresses) <= max {
+		return addresses
+	}
+	scores := map[string]uint64{}
+	for _, address := range addresses {
+		id, ok := owners[address]
+		if !ok {
+			id = address
+		}
+		scores[address] = rendezvousScore(name, id)
+	}
+	ranked := append([]string{}, addresses...)
+	sort.Sort(byScore{addresses: ranked, scores: scores})
+	selected := map[string]bool{}
+	for _, address := range ranked[:max] {
+		selected[address] = true
+	}
+	// Keep the original order of the selected addresses.
+	result := make([]string, 0, max)
+	for _, address := range addresses {
+		if selected[address] {
+			result = append(result, address)
+		}
+	}
+	return result
+}
+
+// rendezvousScore returns the score of id for the Record name.
+func rendezvousScore(name, id string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(id))
+	return h.Sum64()
+}
+
+// byScore sorts addresses by descending score, ties are broken by the address.
+type byScore struct {
+	addresses []string
+	scores    map[string]uint64
+}
+
+func (s byScore) Len() int      { return len(s.addresses) }
+func (s byScore) Swap(i, j int) { s.addresses[i], s.addresses[j] = s.addresses[j], s.addresses[i] }
+func (s byScore) Less(i, j int) bool {
+	a, b := s.addresses[i], s.addresses[j]
+	if s.scores[a] != s.scores[b] {
+		return s.scores[a] > s.scores[b]
+	}
+	return a < b
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// testNodes returns n addresses and their owning Nodes.
+func testNodes(n int) ([]string, map[string]string) {
+	var addresses []string
+	owners := map[string]string{}
+	for i := 1; i <= n; i++ {
+		address := fmt.Sprintf("10.0.0.%d", i)
+		addresses = append(addresses, address)
+		owners[address] = fmt.Sprintf("node%d", i)
+	}
+	return addresses, owners
+}
+
+func TestSelectAddresses(t *testing.T) {
+	addresses, owners := testNodes(20)
+	testScenarios := []struct {
+		addresses []string
+		max       int
+		expect    int
+	}{
+		{addresses: addresses, max: 0, expect: 20},
+		{addresses: addresses, max: 8, expect: 8},
+		{addresses: addresses, max: 20, expect: 20},
+		{addresses: addresses, max: 30, expect: 20},
+		{addresses: addresses[:3], max: 8, expect: 3},
+		{addresses: nil, max: 8, expect: 0},
+	}
+	for _, x := range testScenarios {
+		selected := selectAddresses("www.example.com.", x.addresses, owners, x.max)
+		if len(selected) != x.expect {
+			t.Errorf("expected %d addresses with max %d, got %d", x.expect, x.max, len(selected))
+		}
+		// The selection keeps the original order.
+		i := 0
+		for _, address := range x.addresses {
+			if i < len(selected) && selected[i] == address {
+				i++
+			}
+		}
+		if i != len(selected) {
+			t.Errorf("expected selection %v in the order of %v", selected, x.addresses)
+		}
+	}
+}
+
+func TestSelectAddressesStability(t *testing.T) {
+	addresses, owners := testNodes(20)
+	const name, max = "www.example.com.", 8
+	selected := selectAddresses(name, addresses, owners, max)
+	isSelected := map[string]bool{}
+	for _, address := range selected {
+		isSelected[address] = true
+	}
+
+	testScenarios := []struct {
+		description string
+		modify      func(addresses []string, owners map[string]string) ([]string, map[string]string)
+	}{
+		{
+			description: "reordered addresses",
+			modify: func(addresses []string, owners map[string]string) ([]string, map[string]string) {
+				var result []string
+				for i := len(addresses) - 1; i >= 0; i-- {
+					result = append(result, addresses[i])
+				}
+				return result, owners
+			},
+		},
+		{
+			description: "removed unselected Node",
+			modify: func(addresses []string, owners map[string]string) ([]string, map[string]string) {
+				var result []string
+				removed := false
+				for _, address := range addresses {
+					if !removed && !isSelected[address] {
+						removed = true
+						continue
+					}
+					result = append(result, address)
+				}
+				return result, owners
+			},
+		},
+		{
+			description: "changed address of a Node",
+			modify: func(addresses []string, owners map[string]string) ([]string, map[string]string) {
+				result := map[string]string{}
+				for address, owner := range owners {
+					result[address] = owner
+				}
+				result["10.0.1.1"] = result[addresses[0]]
+				delete(result, addresses[0])
+				return append([]string{"10.0.1.1"}, addresses[1:]...), result
+			},
+		},
+	}
+	for _, x := range testScenarios {
+		modifiedAddresses, modifiedOwners := x.modify(addresses, owners)
+		modified := selectAddresses(name, modifiedAddresses, modifiedOwners, max)
+		if len(modified) != max {
+			t.Errorf("%s: expected %d addresses, got %d", x.description, max, len(modified))
+		}
+		nodes := func(addresses []string, owners map[string]string) map[string]bool {
+			result := map[string]bool{}
+			for _, address := range addresses {
+				result[owners[address]] = true
+			}
+			return result
+		}
+		if !reflect.DeepEqual(nodes(selected, owners), nodes(modified, modifiedOwners)) {
+			t.Errorf("%s: expected the Nodes of %v, got %v", x.description, selected, modified)
+		}
+	}
+
+	// Adding Nodes replaces at most as many selected addresses as were added.
+	added := append(append([]string{}, addresses...), "10.0.2.1", "10.0.2.2")
+	modified := selectAddresses(name, added, owners, max)
+	kept := 0
+	for _, address := range modified {
+		if isSelected[address] {
+			kept++
+		}
+	}
+	if kept < max-2 {
+		t.Errorf("expected at least %d of %v to be kept after adding 2 Nodes, got %v", max-2, selected, modified)
+	}
+
+	// Other Records select other addresses.
+	if reflect.DeepEqual(selected, selectAddresses("api.example.com.", addresses, owners, max)) {
+		t.Errorf("expected different selections for different Records")
+	}
+}
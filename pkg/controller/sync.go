@@ -10,140 +10,496 @@ package controller
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/kr/pretty"
 
 	"k8s.io/kubernetes/federation/pkg/dnsprovider"
 	"k8s.io/kubernetes/federation/pkg/dnsprovider/rrstype"
-	"k8s.io/kubernetes/pkg/api"
+	utilerrors "k8s.io/kubernetes/pkg/util/errors"
 
 	k8sutil "github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes"
+	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/changeset"
+	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/routing"
+	netutil "github.com/wikiwi/kube-dns-sync/pkg/util/net"
 )
 
 // sync starts the syncing process.
-func (c *Controller) sync() error {
+func (c *Controller) sync() (err error) {
 	c.log.Infof("Perform sync now")
-	var zone dnsprovider.Zone
+	start := time.Now()
+	defer func() { observeSync(start, err) }()
+	plans, err := c.plan(!c.dryRun)
+	var errs []error
+	if agg, ok := err.(utilerrors.Aggregate); ok {
+		errs = append(errs, agg.Errors()...)
+	} else if err != nil {
+		errs = append(errs, err)
+	}
+	for _, p := range plans {
+		recordsUnchanged.WithLabelValues(p.zone.name).Set(float64(p.unchanged))
+		if c.dryRun {
+			for _, change := range p.changes {
+				c.log.Infof("Dry run, skipping %s", change)
+			}
+			continue
+		}
+		if !c.IsLeader() {
+			c.log.Warnf("Lost leadership, not applying the remaining changes")
+			break
+		}
+		if err := c.applyChanges(p.rrs, p.changes); err != nil {
+			// The state of the zone is unknown after a failed apply.
+			p.zone.cache = nil
+			errs = append(errs, newSyncError(errorKindApply, err))
+			continue
+		}
+		if p.zone.cache != nil {
+			p.zone.cache.apply(p.changes)
+		}
+	}
+	// Health checks are only removed once no zone might still use them.
+	if len(errs) == 0 && !c.dryRun && c.IsLeader() {
+		if err := c.removeStaleHealthChecks(); err != nil {
+			errs = append(errs, newSyncError(errorKindProvider, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
 
-	c.log.Infof("Looking for Zone %q", c.zoneName)
+// plan computes the Changes of all zones. Missing zones are created when create
+// and the CreateZone option are set, missing health checks when create and
+// HealthCheckPort are set. The plans of the zones that succeeded are
+// returned together with the errors of the others. Zones are only listed from the
+// DNS Provider when the Records of a zone are not cached, see DriftCheckInterval.
+func (c *Controller) plan(create bool) ([]zonePlan, error) {
 	zones, supported := c.dns.Zones()
 	if !supported {
-		return fmt.Errorf("DNS Provider %q doesn't support Zones", c.dnsProvider)
+		return nil, newSyncError(errorKindUnsupported, fmt.Errorf("DNS Provider %q doesn't support Zones", c.dnsProvider))
 	}
 
-	zoneList, err := zones.List()
+	var zoneList []dnsprovider.Zone
+	if c.needsZoneList() {
+		var err error
+		zoneList, err = zones.List()
+		if err != nil {
+			return nil, newSyncError(errorKindProvider, err)
+		}
+		providerLists.WithLabelValues("zones").Inc()
+	}
+	endpoints, err := c.zoneEndpoints()
 	if err != nil {
-		return err
+		return nil, newSyncError(errorKindSource, err)
 	}
+	if err := c.bindHealthChecks(endpoints, create); err != nil {
+		return nil, newSyncError(errorKindProvider, err)
+	}
+	var plans []zonePlan
+	var errs []error
+	for _, z := range c.zones {
+		p, err := c.planZone(z, zones, zoneList, endpoints[z], create)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		plans = append(plans, p)
+	}
+	return plans, utilerrors.NewAggregate(errs)
+}
+
+// planZone computes the Changes syncing the Records of endpoints to zone z.
+func (c *Controller) planZone(z *zone, zones dnsprovider.Zones, zoneList []dnsprovider.Zone, endpoints []Endpoint, create bool) (zonePlan, error) {
+	if z.cache.fresh(c.driftCheckInterval) {
+		c.log.Debugf("Using cached Records of Zone %q", z.name)
+		return c.planRecords(z, z.cache.rrs, z.cache.records, endpoints), nil
+	}
+
+	var dnsZone dnsprovider.Zone
+	c.log.Infof("Looking for Zone %q", z.name)
 	for _, x := range zoneList {
-		if x.Name() == c.zoneName {
-			zone = x
+		if x.Name() == z.name {
+			dnsZone = x
 			break
 		}
 	}
-	if zone == nil {
-		return fmt.Errorf("Zone %q not found, waiting until one is created", c.zoneName)
+	exists := dnsZone != nil
+	if !exists && c.createZone {
+		var err error
+		if create {
+			dnsZone, err = c.createDNSZone(zones, z.name)
+			exists = true
+		} else {
+			c.log.Infof("Zone %q not found, it would be created", z.name)
+			dnsZone, err = zones.New(z.name)
+		}
+		if err != nil {
+			return zonePlan{}, newSyncErrorf(errorKindZoneCreation, err, "failed to create Zone %q", z.name)
+		}
+	}
+	if dnsZone == nil {
+		return zonePlan{}, newSyncError(errorKindZoneNotFound, fmt.Errorf("Zone %q not found, waiting until one is created", z.name))
 	}
 
-	rrs, supported := zone.ResourceRecordSets()
+	rrs, supported := dnsZone.ResourceRecordSets()
 	if !supported {
-		return fmt.Errorf("Zone %q doesn't support ResourceRecordSets", c.zoneName)
+		return zonePlan{}, newSyncError(errorKindUnsupported, fmt.Errorf("Zone %q doesn't support ResourceRecordSets", z.name))
+	}
+	recordList := []dnsprovider.ResourceRecordSet{}
+	z.cache = nil
+	if exists {
+		var err error
+		recordList, err = rrs.List()
+		if err != nil {
+			return zonePlan{}, newSyncError(errorKindProvider, err)
+		}
+		providerLists.WithLabelValues("records").Inc()
+		z.cache = newRecordCache(rrs, recordList)
 	}
+	return c.planRecords(z, rrs, recordList, endpoints), nil
+}
 
-	managedRecords := c.managedResourceRecordSets(rrs)
-	return c.syncRecordSets(managedRecords, rrs)
+// planRecords computes the Changes syncing the Records of endpoints to the
+// Records recordList of zone z.
+func (c *Controller) planRecords(z *zone, rrs dnsprovider.ResourceRecordSets, recordList []dnsprovider.ResourceRecordSet, endpoints []Endpoint) zonePlan {
+	managedRecords := c.managedResourceRecordSets(rrs, z.ttl, endpoints)
+	changes, unchanged := c.planRecordSets(z.name, managedRecords, recordList, rrs, z.ttl)
+	return zonePlan{zone: z, rrs: rrs, changes: changes, unchanged: unchanged}
 }
 
-// syncRecordSets will sync given list of RecordSets to the DNS Provider.
-func (c *Controller) syncRecordSets(managedRecords []dnsprovider.ResourceRecordSet, rrs dnsprovider.ResourceRecordSets) error {
-	c.log.Infof("Sync A Records")
+// nsType is the Resource Record Set type of the name servers of a zone.
+const nsType = rrstype.RrsType("NS")
+
+// createDNSZone creates the zone name at the DNS Provider and logs its name servers.
+// Errors of the DNS Provider are returned unwrapped.
+func (c *Controller) createDNSZone(zones dnsprovider.Zones, name string) (dnsprovider.Zone, error) {
+	c.log.Infof("Creating Zone %q", name)
+	zone, err := zones.New(name)
+	if err != nil {
+		return nil, err
+	}
+	zone, err = zones.Add(zone)
+	if err != nil {
+		return nil, err
+	}
+	rrs, supported := zone.ResourceRecordSets()
+	if !supported {
+		return zone, nil
+	}
 	recordList, err := rrs.List()
 	if err != nil {
-		return err
+		c.log.Warnf("Failed to list name servers of Zone %q: %v", name, err)
+		return zone, nil
+	}
+	var nameServers []string
+	for _, x := range recordList {
+		if x.Type() == nsType && x.Name() == name {
+			nameServers = append(nameServers, x.Rrdatas()...)
+		}
 	}
+	c.log.Infof("Created Zone %q with name servers %s", name, strings.Join(nameServers, ", "))
+	return zone, nil
+}
+
+// planRecordSets computes the Changes syncing the managed RecordSets to a zone
+// with the existing recordList, and counts the managed RecordSets that are up to date.
+func (c *Controller) planRecordSets(zoneName string, managedRecords, recordList []dnsprovider.ResourceRecordSet, rrs dnsprovider.ResourceRecordSets, ttl int64) ([]Change, int) {
+	changes := []Change{}
+	unchanged := 0
+	ownerships := c.recordOwnership(recordList)
 	for _, record := range managedRecords {
-		create := true
-		for _, x := range recordList {
-			if x.Type() != rrstype.A {
+		switch ownerships[record.Name()] {
+		case foreign:
+			c.log.Warnf("Skipping Record %q, it is not owned by %q", record.Name(), c.ownerID)
+			continue
+		case occupied:
+			c.log.Warnf("Skipping Record %q, the name holds a CNAME Record", record.Name())
+			continue
+		case unmarked:
+			if !c.adoptExisting {
+				c.log.Warnf("Skipping Record %q, it has no ownership Record %q", record.Name(), ownershipName(record.Name()))
 				continue
 			}
-			if x.Name() == record.Name() {
-				if !k8sutil.EqualRRS(x, record) {
-					c.log.Infof("Remove diverged Record %q", record.Name())
-					pretty.Pdiff(c.log, x, record)
-					err := rrs.Remove(x)
-					if err != nil {
-						return err
-					}
-				} else {
-					create = false
-				}
+			c.log.Infof("Adopting Records of %q", record.Name())
+			fallthrough
+		case unclaimed:
+			changes = append(changes, newChange(ActionAdd, zoneName, c.newOwnershipRecord(rrs, record.Name(), ttl)))
+			ownerships[record.Name()] = owned
+		}
+		var existing dnsprovider.ResourceRecordSet
+		for _, x := range recordList {
+			if routing.Key(x) == routing.Key(record) {
+				existing = x
+				break
 			}
 		}
-		if create {
-			c.log.Infof("Adding A Record %q", record.Name())
-			_, err := rrs.Add(record)
-			if err != nil {
-				return err
+		switch {
+		case existing == nil:
+			changes = append(changes, newChange(ActionAdd, zoneName, record))
+		case !k8sutil.EqualRRS(existing, record):
+			changes = append(changes, newUpdateChange(zoneName, existing, record))
+		default:
+			unchanged++
+		}
+	}
+
+	if c.keepStaleRecords {
+		return changes, unchanged
+	}
+	return append(changes, c.planStaleRecordSets(zoneName, managedRecords, recordList, ownerships)...), unchanged
+}
+
+// planStaleRecordSets computes the Changes removing owned RecordSets that are not
+// in the list of managed RecordSets anymore.
+func (c *Controller) planStaleRecordSets(zoneName string, managedRecords, recordList []dnsprovider.ResourceRecordSet, ownerships map[string]ownership) []Change {
+	changes := []Change{}
+	desiredNames := map[string]bool{}
+	desired := map[string]bool{}
+	for _, record := range managedRecords {
+		desiredNames[record.Name()] = true
+		desired[routing.Key(record)] = true
+	}
+	// Remove address Records before their ownership Records, so that an interrupted
+	// sync never leaves an address Record without its owner behind.
+	for _, recordType := range []rrstype.RrsType{rrstype.A, rrstype.AAAA} {
+		for _, x := range recordList {
+			if x.Type() != recordType || ownerships[x.Name()] != owned || desired[routing.Key(x)] {
+				continue
 			}
+			changes = append(changes, newChange(ActionRemove, zoneName, x))
+		}
+	}
+	for _, x := range recordList {
+		if !c.isOwnershipRecord(x) || desiredNames[strings.TrimPrefix(x.Name(), ownershipPrefix)] {
+			continue
 		}
+		changes = append(changes, newChange(ActionRemove, zoneName, x))
 	}
+	return changes
+}
 
+// applyChanges applies changes to the DNS Provider. Providers implementing
+// changeset.Batcher apply all changes atomically, otherwise additions are
+// applied before replacements and removals, see applyChangesInOrder.
+func (c *Controller) applyChanges(rrs dnsprovider.ResourceRecordSets, changes []Change) error {
+	c.log.Infof("Sync Records")
+	batcher, ok := rrs.(changeset.Batcher)
+	if !ok {
+		return c.applyChangesInOrder(rrs, changes)
+	}
+	var removals, additions []dnsprovider.ResourceRecordSet
+	for _, change := range changes {
+		c.logChange(change)
+		switch change.Action {
+		case ActionAdd:
+			additions = append(additions, change.record)
+		case ActionRemove:
+			removals = append(removals, change.record)
+		case ActionUpdate:
+			removals = append(removals, change.old)
+			additions = append(additions, change.record)
+		}
+	}
+	if err := batcher.Apply(removals, additions); err != nil {
+		return err
+	}
+	for _, change := range changes {
+		recordChanges.WithLabelValues(change.Zone, string(change.Action)).Inc()
+		c.recordChangeEvents(change)
+	}
 	return nil
 }
 
-// managedResourceRecordSets returns a list of managed ResourceRecordSets.
-func (c *Controller) managedResourceRecordSets(rrs dnsprovider.ResourceRecordSets) []dnsprovider.ResourceRecordSet {
-	var nodes []*api.Node
-	for _, x := range c.cache.List() {
-		nodes = append(nodes, x.(*api.Node))
+// applyChangesInOrder applies changes one by one, so that Records are never
+// missing for longer than necessary: additions go first, then replacements and
+// last removals. A replaced Record is restored when adding its replacement fails.
+func (c *Controller) applyChangesInOrder(rrs dnsprovider.ResourceRecordSets, changes []Change) error {
+	for _, action := range []Action{ActionAdd, ActionUpdate, ActionRemove} {
+		for _, change := range changes {
+			if change.Action != action {
+				continue
+			}
+			c.logChange(change)
+			switch change.Action {
+			case ActionAdd:
+				if _, err := rrs.Add(change.record); err != nil {
+					return err
+				}
+			case ActionRemove:
+				if err := rrs.Remove(change.record); err != nil {
+					return err
+				}
+			case ActionUpdate:
+				if err := rrs.Remove(change.old); err != nil {
+					return err
+				}
+				if _, err := rrs.Add(change.record); err != nil {
+					if _, restoreErr := rrs.Add(change.old); restoreErr != nil {
+						c.log.Errorf("Failed to restore %s Record %q: %v", change.Type, change.Name, restoreErr)
+					}
+					return err
+				}
+			}
+			recordChanges.WithLabelValues(change.Zone, string(change.Action)).Inc()
+			c.recordChangeEvents(change)
+		}
 	}
+	return nil
+}
 
-	addressTypes := c.addressTypes
-	apexInGroup := false
-	if c.apexAddressType != "" {
-		for _, x := range c.addressTypes {
-			if c.apexAddressType == x {
-				apexInGroup = true
-				break
+// logChange logs change before it is applied.
+func (c *Controller) logChange(change Change) {
+	switch change.Action {
+	case ActionAdd:
+		c.log.Infof("Adding %s Record %q", change.Type, change.Name)
+	case ActionRemove:
+		c.log.Infof("Remove stale %s Record %q", change.Type, change.Name)
+	case ActionUpdate:
+		c.log.Infof("Replace diverged %s Record %q", change.Type, change.Name)
+		pretty.Pdiff(c.log, change.old, change.record)
+	}
+}
+
+// zoneEndpoints collects the Endpoints of all Sources and assigns each of them
+// to the most specific zone containing its name.
+func (c *Controller) zoneEndpoints() (map[*zone][]Endpoint, error) {
+	sources := append([]Source{}, c.sources...)
+	for _, z := range c.zones {
+		sources = append(sources, z.sources...)
+	}
+	var endpoints []Endpoint
+	for _, source := range sources {
+		list, err := source.Endpoints()
+		if err != nil {
+			return nil, err
+		}
+		for _, endpoint := range list {
+			endpoint.DNSName = strings.ToLower(endpoint.DNSName)
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	result := map[*zone][]Endpoint{}
+	for _, endpoint := range c.dropTakeovers(endpoints) {
+		z, err := c.zoneFor(endpoint.DNSName)
+		if err != nil {
+			c.log.Warnf("Skipping Endpoint: %v", err)
+			continue
+		}
+		result[z] = append(result[z], endpoint)
+	}
+	return result, nil
+}
+
+// dropTakeovers removes the annotated Endpoints whose names are published
+// by Endpoints that are not annotated.
+func (c *Controller) dropTakeovers(endpoints []Endpoint) []Endpoint {
+	published := map[string]bool{}
+	for _, endpoint := range endpoints {
+		if !endpoint.Annotated {
+			published[endpoint.DNSName] = true
+		}
+	}
+	result := []Endpoint{}
+	warned := map[string]bool{}
+	for _, endpoint := range endpoints {
+		if endpoint.Annotated && published[endpoint.DNSName] {
+			if !warned[endpoint.DNSName] {
+				c.log.Warnf("Skipping annotated name %q, it is already published", endpoint.DNSName)
+				warned[endpoint.DNSName] = true
+			}
+			continue
+		}
+		result = append(result, endpoint)
+	}
+	return result
+}
+
+// recordKey identifies the Records built from Endpoints by name and routing policy.
+type recordKey struct {
+	name   string
+	policy routing.Policy
+}
+
+// managedResourceRecordSets returns a list of managed ResourceRecordSets built
+// from endpoints. Endpoints with a routing policy are built into Records of that
+// policy when rrs implements routing.Router, otherwise the policy is ignored.
+// Records hold at most MaxAddressesPerRecord addresses, see selectAddresses.
+func (c *Controller) managedResourceRecordSets(rrs dnsprovider.ResourceRecordSets, ttl int64, endpoints []Endpoint) []dnsprovider.ResourceRecordSet {
+	router, supportsRouting := rrs.(routing.Router)
+	groups := map[recordKey][]string{}
+	var keys []recordKey
+	warned := false
+	for _, endpoint := range endpoints {
+		key := recordKey{name: endpoint.DNSName, policy: endpoint.Policy}
+		if key.policy != (routing.Policy{}) && !supportsRouting {
+			if !warned {
+				c.log.Warnf("DNS Provider %q doesn't support routing policies, ignoring them", c.dnsProvider)
+				warned = true
 			}
+			key.policy = routing.Policy{}
 		}
-		if !apexInGroup {
-			addressTypes = append(addressTypes, c.apexAddressType)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
 		}
+		groups[key] = appendUnique(groups[key], c.filterAddresses(endpoint.Targets)...)
 	}
 
+	owners := c.addressOwners()
 	sets := []dnsprovider.ResourceRecordSet{}
-	for _, addressType := range addressTypes {
-		typeString := strings.ToLower(string(addressType))
-		groupAddresses := []string{}
-		for _, node := range nodes {
-			if !k8sutil.IsNodeReady(node) {
+	for _, key := range keys {
+		byType := map[rrstype.RrsType][]string{}
+		for _, address := range groups[key] {
+			recordType, _ := netutil.RecordType(address)
+			byType[recordType] = append(byType[recordType], address)
+		}
+		for _, recordType := range []rrstype.RrsType{rrstype.A, rrstype.AAAA} {
+			if len(byType[recordType]) == 0 {
 				continue
 			}
-			addresses := []string{}
-			for _, x := range node.Status.Addresses {
-				if x.Type == addressType {
-					addresses = append(addresses, x.Address)
-				}
+			addresses := selectAddresses(key.name, byType[recordType], owners, c.maxAddresses)
+			if len(addresses) < len(byType[recordType]) {
+				c.log.Debugf("Limiting %s Record %q to %d of %d addresses", recordType, key.name, len(addresses), len(byType[recordType]))
 			}
-			if len(addresses) == 0 {
+			if key.policy != (routing.Policy{}) {
+				sets = append(sets, router.NewRecord(key.name, addresses, ttl, recordType, key.policy))
 				continue
 			}
-			groupAddresses = append(groupAddresses, addresses...)
+			record := rrs.New(key.name, addresses, ttl, recordType)
+			sets = append(sets, record)
 		}
-		if len(groupAddresses) == 0 {
+	}
+	return sets
+}
+
+// filterAddresses returns the valid addresses that are synced according to the configured
+// IPFamily in their canonical form, e.g. IPv4-mapped IPv6 addresses as IPv4 addresses.
+func (c *Controller) filterAddresses(addresses []string) []string {
+	result := []string{}
+	for _, address := range addresses {
+		recordType, err := netutil.RecordType(address)
+		if err != nil {
+			c.log.Warnf("Skipping address: %v", err)
 			continue
 		}
-		if addressType == c.apexAddressType {
-			record := rrs.New(c.zoneName, groupAddresses, c.ttl, rrstype.A)
-			sets = append(sets, record)
+		if c.ipFamily.includes(recordType) {
+			result = append(result, netutil.NormalizeAddress(address))
 		}
-		if addressType != c.apexAddressType || apexInGroup {
-			name := typeString + "." + c.zoneName
-			record := rrs.New(name, groupAddresses, c.ttl, rrstype.A)
-			sets = append(sets, record)
+	}
+	return result
+}
+
+// appendUnique appends the elements of add to list that are not already contained.
+func appendUnique(list []string, add ...string) []string {
+	for _, x := range add {
+		found := false
+		for _, y := range list {
+			if x == y {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, x)
 		}
 	}
-	return sets
+	return list
 }
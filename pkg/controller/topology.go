@@ -0,0 +1,35 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+)
+
+// topologyLabels returns the DNS labels of the failure domain zone and region
+// of node, like "us-east-1a" and "us-east-1", for its topology Records.
+func (s *nodeSource) topologyLabels(node *api.Node) []string {
+	if !s.topologyRecords {
+		return nil
+	}
+	var result []string
+	for _, key := range []string{unversioned.LabelZoneFailureDomain, unversioned.LabelZoneRegion} {
+		value, ok := node.Labels[key]
+		if !ok {
+			continue
+		}
+		label := groupLabel(value)
+		if label == "" {
+			s.log.Warnf("Skipping topology Record of Node %q: label %s=%q is not a valid DNS label", node.Name, key, value)
+			continue
+		}
+		result = appendUnique(result, label)
+	}
+	return result
+}
@@ -11,61 +11,238 @@ import (
 	"reflect"
 	"time"
 
+	"github.com/Sirupsen/logrus"
 	"github.com/kr/pretty"
 
 	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/apis/extensions"
 	"k8s.io/kubernetes/pkg/client/cache"
+	"k8s.io/kubernetes/pkg/client/unversioned"
 	"k8s.io/kubernetes/pkg/controller/framework"
+	"k8s.io/kubernetes/pkg/labels"
 	"k8s.io/kubernetes/pkg/runtime"
 	"k8s.io/kubernetes/pkg/watch"
-
-	k8sutil "github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes"
 )
 
-// watch watches the Kubernetes API and requests a sync when a change was detected.
-func (c *Controller) watch() {
-	c.log.Infof("Start kubernetes watcher")
+// resyncPeriod of the informers.
+const resyncPeriod = time.Second * 60
+
+// watcher runs an informer and notifies its Changes channel when a relevant
+// change was detected. A watcher is shared by the Sources of all zones.
+type watcher struct {
+	notifier
+	name       string
+	log        *logrus.Logger
+	store      cache.Store
+	controller *framework.Controller
+}
+
+// Run starts the informer and returns immediately.
+func (w *watcher) Run(stopCh <-chan struct{}) {
+	w.log.Infof("Start kubernetes %s watcher", w.name)
+	go w.controller.Run(stopCh)
+}
+
+// List returns all objects in the store of the informer.
+func (w *watcher) List() []interface{} {
+	return w.store.List()
+}
+
+// HasSynced returns true when the informer completed its initial listing.
+func (w *watcher) HasSynced() bool {
+	return w.controller.HasSynced()
+}
+
+// newNodeWatcher creates a watcher for the Nodes matching selector, the zones
+// select their Nodes from it.
+func newNodeWatcher(client unversioned.Interface, selector labels.Selector, log *logrus.Logger) *watcher {
+	w := &watcher{notifier: newNotifier(), name: "node", log: log}
+	w.store, w.controller = newNodeInformer(client, selector, log, w.notify)
+	return w
+}
+
+// nodeSelector returns the selector of the zones when all of them share the same
+// one, so that the Kubernetes API only sends the selected Nodes. Otherwise all
+// Nodes are watched.
+func nodeSelector(zones []ZoneOptions) labels.Selector {
+	if len(zones) == 0 || zones[0].Selector == nil {
+		return labels.Everything()
+	}
+	for _, z := range zones[1:] {
+		if z.Selector == nil || z.Selector.String() != zones[0].Selector.String() {
+			return labels.Everything()
+		}
+	}
+	return zones[0].Selector
+}
+
+// newServiceWatcher creates a watcher for all Services.
+func newServiceWatcher(client unversioned.Interface, log *logrus.Logger) *watcher {
+	w := &watcher{notifier: newNotifier(), name: "service", log: log}
+	w.store, w.controller = newServiceInformer(client, log, w.notify)
+	return w
+}
 
-	resyncPeriod := time.Second * 60
+// newIngressWatcher creates a watcher for all Ingresses.
+func newIngressWatcher(client unversioned.Interface, log *logrus.Logger) *watcher {
+	w := &watcher{notifier: newNotifier(), name: "ingress", log: log}
+	w.store, w.controller = newIngressInformer(client, log, w.notify)
+	return w
+}
+
+// newNodeInformer returns an informer watching the Nodes matching selector,
+// which calls notify when a relevant change was detected.
+func newNodeInformer(client unversioned.Interface, selector labels.Selector, log *logrus.Logger, notify func()) (cache.Store, *framework.Controller) {
 	nodeEventHandler := framework.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
+			informerEvents.WithLabelValues("node", "add").Inc()
 			node := obj.(*api.Node)
-			c.log.Infof("CREATE %s/%s", node.Namespace, node.Name)
-			c.requestSync()
+			log.Infof("CREATE %s/%s", node.Namespace, node.Name)
+			notify()
 		},
 		DeleteFunc: func(obj interface{}) {
-			node := obj.(*api.Node)
-			c.log.Infof("DELETE %s/%s", node.Namespace, node.Name)
-			c.requestSync()
+			informerEvents.WithLabelValues("node", "delete").Inc()
+			if node, ok := deletedObject(obj).(*api.Node); ok {
+				log.Infof("DELETE %s/%s", node.Namespace, node.Name)
+			}
+			notify()
 		},
 		UpdateFunc: func(oldI, curI interface{}) {
+			informerEvents.WithLabelValues("node", "update").Inc()
 			cur := curI.(*api.Node)
 			old := oldI.(*api.Node)
-			if k8sutil.IsNodeReady(old) != k8sutil.IsNodeReady(cur) || !reflect.DeepEqual(old.Status.Addresses, cur.Status.Addresses) {
-				c.log.Infof("UPDATE %s/%s", cur.Namespace, cur.Name)
-				pretty.Pdiff(c.log, old.Status.Addresses, cur.Status.Addresses)
-				c.requestSync()
+			if eligibilityChanged(old, cur) || annotationsChanged(old, cur) ||
+				!reflect.DeepEqual(old.Status.Addresses, cur.Status.Addresses) ||
+				!reflect.DeepEqual(old.Labels, cur.Labels) {
+				log.Infof("UPDATE %s/%s", cur.Namespace, cur.Name)
+				pretty.Pdiff(log, old.Status.Addresses, cur.Status.Addresses)
+				notify()
 			}
 		},
 	}
 
-	store, controller := framework.NewInformer(
+	return framework.NewInformer(
 		&cache.ListWatch{
 			ListFunc: func(opts api.ListOptions) (runtime.Object, error) {
-				opts.LabelSelector = c.selector
-				return c.client.Nodes().List(opts)
+				opts.LabelSelector = selector
+				return client.Nodes().List(opts)
 			},
 			WatchFunc: func(opts api.ListOptions) (watch.Interface, error) {
-				opts.LabelSelector = c.selector
-				return c.client.Nodes().Watch(opts)
+				opts.LabelSelector = selector
+				return client.Nodes().Watch(opts)
 			},
 		},
 		&api.Node{},
 		resyncPeriod,
 		nodeEventHandler,
 	)
+}
 
-	c.cache = store
+// newServiceInformer returns an informer watching all Services, which calls notify
+// when a change of a published Service was detected.
+func newServiceInformer(client unversioned.Interface, log *logrus.Logger, notify func()) (cache.Store, *framework.Controller) {
+	serviceEventHandler := framework.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			informerEvents.WithLabelValues("service", "add").Inc()
+			service := obj.(*api.Service)
+			if isServicePublished(service) {
+				log.Infof("CREATE %s/%s", service.Namespace, service.Name)
+				notify()
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			informerEvents.WithLabelValues("service", "delete").Inc()
+			service, ok := deletedObject(obj).(*api.Service)
+			if !ok {
+				// Without the final state we cannot tell whether it was published.
+				notify()
+				return
+			}
+			if isServicePublished(service) {
+				log.Infof("DELETE %s/%s", service.Namespace, service.Name)
+				notify()
+			}
+		},
+		UpdateFunc: func(oldI, curI interface{}) {
+			informerEvents.WithLabelValues("service", "update").Inc()
+			cur := curI.(*api.Service)
+			old := oldI.(*api.Service)
+			if !isServicePublished(old) && !isServicePublished(cur) {
+				return
+			}
+			if isServicePublished(old) != isServicePublished(cur) ||
+				old.Spec.Type != cur.Spec.Type ||
+				!reflect.DeepEqual(old.Status.LoadBalancer, cur.Status.LoadBalancer) {
+				log.Infof("UPDATE %s/%s", cur.Namespace, cur.Name)
+				notify()
+			}
+		},
+	}
+
+	return framework.NewInformer(
+		&cache.ListWatch{
+			ListFunc: func(opts api.ListOptions) (runtime.Object, error) {
+				return client.Services(api.NamespaceAll).List(opts)
+			},
+			WatchFunc: func(opts api.ListOptions) (watch.Interface, error) {
+				return client.Services(api.NamespaceAll).Watch(opts)
+			},
+		},
+		&api.Service{},
+		resyncPeriod,
+		serviceEventHandler,
+	)
+}
+
+// newIngressInformer returns an informer watching all Ingresses, which calls notify
+// when a relevant change was detected.
+func newIngressInformer(client unversioned.Interface, log *logrus.Logger, notify func()) (cache.Store, *framework.Controller) {
+	ingressEventHandler := framework.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			informerEvents.WithLabelValues("ingress", "add").Inc()
+			ingress := obj.(*extensions.Ingress)
+			log.Infof("CREATE %s/%s", ingress.Namespace, ingress.Name)
+			notify()
+		},
+		DeleteFunc: func(obj interface{}) {
+			informerEvents.WithLabelValues("ingress", "delete").Inc()
+			if ingress, ok := deletedObject(obj).(*extensions.Ingress); ok {
+				log.Infof("DELETE %s/%s", ingress.Namespace, ingress.Name)
+			}
+			notify()
+		},
+		UpdateFunc: func(oldI, curI interface{}) {
+			informerEvents.WithLabelValues("ingress", "update").Inc()
+			cur := curI.(*extensions.Ingress)
+			old := oldI.(*extensions.Ingress)
+			if !reflect.DeepEqual(old.Spec.Rules, cur.Spec.Rules) ||
+				!reflect.DeepEqual(old.Status.LoadBalancer, cur.Status.LoadBalancer) {
+				log.Infof("UPDATE %s/%s", cur.Namespace, cur.Name)
+				notify()
+			}
+		},
+	}
+
+	return framework.NewInformer(
+		&cache.ListWatch{
+			ListFunc: func(opts api.ListOptions) (runtime.Object, error) {
+				return client.Extensions().Ingress(api.NamespaceAll).List(opts)
+			},
+			WatchFunc: func(opts api.ListOptions) (watch.Interface, error) {
+				return client.Extensions().Ingress(api.NamespaceAll).Watch(opts)
+			},
+		},
+		&extensions.Ingress{},
+		resyncPeriod,
+		ingressEventHandler,
+	)
+}
 
-	go controller.Run(c.stopCh)
+// deletedObject unwraps the tombstone an informer delivers on deletion when
+// it missed the delete event and only noticed the object gone on relist.
+func deletedObject(obj interface{}) interface{} {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return tombstone.Obj
+	}
+	return obj
 }
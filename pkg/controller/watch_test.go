@@ -0,0 +1,30 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/client/cache"
+)
+
+func TestDeletedObject(t *testing.T) {
+	service := &api.Service{ObjectMeta: api.ObjectMeta{Namespace: "default", Name: "web"}}
+	testScenarios := []struct {
+		Obj interface{}
+	}{
+		{Obj: service},
+		{Obj: cache.DeletedFinalStateUnknown{Key: "default/web", Obj: service}},
+	}
+	for _, scenario := range testScenarios {
+		if got, ok := deletedObject(scenario.Obj).(*api.Service); !ok || got != service {
+			t.Errorf("deletedObject(%#v) = %#v, want %#v", scenario.Obj, got, service)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package controller
+
+import (
+	"fmt"
+
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/labels"
+)
+
+// ZoneOptions configure the Records synced to a single zone.
+type ZoneOptions struct {
+	// Name of the zone, like "example.com.", required. Zones are looked up by
+	// name, so the name must be unique among the zones and at the DNS Provider.
+	Name string
+
+	// TTL value of Records, defaults to 60.
+	TTL int64
+
+	// AddressTypes are the address types to sync to DNS,
+	// required when ApexAddressType is not set.
+	AddressTypes []api.NodeAddressType
+
+	// ApexAddressType defines which address should be sync to the apex zone,
+	// required when AddressTypes is not set.
+	ApexAddressType api.NodeAddressType
+
+	// Selector to target only specific Nodes.
+	Selector labels.Selector
+
+	// RecordNameTemplate is a text/template for the names of the address type Records,
+	// defaults to DefaultRecordNameTemplate.
+	RecordNameTemplate string
+
+	// NodeRecords enables publishing an additional Record per Node.
+	NodeRecords bool
+
+	// GroupByLabel enables publishing an additional Record per value of the Node
+	// label, like "pool-a.externalip.example.com." for the label value "pool-a".
+	GroupByLabel string
+
+	// TopologyRecords enables publishing an additional Record per failure domain
+	// zone and region of the Nodes, like "us-east-1a.externalip.example.com.".
+	TopologyRecords bool
+
+	// LatencyRouting publishes the address type Records per region of the Nodes
+	// with a latency routing policy, when the DNS Provider supports it.
+	LatencyRouting bool
+
+	// WeightedRecords publishes the address type Records once per Node with a
+	// weighted routing policy, when the DNS Provider supports it.
+	WeightedRecords bool
+
+	// SyncServices enables syncing of Services annotated with AnnotationPublish.
+	SyncServices bool
+
+	// SyncIngresses enables syncing of the hosts of Ingresses that are inside of the zone.
+	SyncIngresses bool
+}
+
+// zone is a zone synced by the Controller.
+type zone struct {
+	name    string
+	ttl     int64
+	sources []Source
+	// cache holds the Records of the zone at the DNS Provider, nil when unknown.
+	cache *recordCache
+}
+
+// zoneOptions returns the configured zones, with the zone of the top level
+// options first when ZoneName is set.
+func zoneOptions(opts *Options) []ZoneOptions {
+	var result []ZoneOptions
+	if opts.ZoneName != "" {
+		result = append(result, ZoneOptions{
+			Name:               opts.ZoneName,
+			TTL:                opts.TTL,
+			AddressTypes:       opts.AddressTypes,
+			ApexAddressType:    opts.ApexAddressType,
+			Selector:           opts.Selector,
+			RecordNameTemplate: opts.RecordNameTemplate,
+			NodeRecords:        opts.NodeRecords,
+			GroupByLabel:       opts.GroupByLabel,
+			TopologyRecords:    opts.TopologyRecords,
+			LatencyRouting:     opts.LatencyRouting,
+			WeightedRecords:    opts.WeightedRecords,
+			SyncServices:       opts.SyncServices,
+			SyncIngresses:      opts.SyncIngresses,
+		})
+	}
+	return append(result, opts.Zones...)
+}
+
+// newZone creates the zone of opts and its Sources, which share the watchers of c.
+func (c *Controller) newZone(opts ZoneOptions, serviceAddressType api.NodeAddressType) (*zone, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("please provide a zone name")
+	}
+	for _, x := range c.zones {
+		if x.name == opts.Name {
+			return nil, fmt.Errorf("zone %q is configured more than once", opts.Name)
+		}
+	}
+	if len(opts.AddressTypes) == 0 && opts.ApexAddressType == "" {
+		return nil, fmt.Errorf("zone %q: please provide either AddressTypes or ApexAddressType", opts.Name)
+	}
+	if opts.LatencyRouting && opts.WeightedRecords {
+		return nil, fmt.Errorf("zone %q: LatencyRouting and WeightedRecords can't be combined", opts.Name)
+	}
+
+	recordNameTemplate := opts.RecordNameTemplate
+	if recordNameTemplate == "" {
+		recordNameTemplate = DefaultRecordNameTemplate
+	}
+	tmpl, err := parseRecordNameTemplate(recordNameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("zone %q: invalid record name template: %v", opts.Name, err)
+	}
+
+	z := &zone{name: opts.Name, ttl: opts.TTL}
+	if z.ttl == 0 {
+		z.ttl = 60
+	}
+	nodes := newNodeSource(c.nodes, c.log, nodeRecordOptions{
+		zoneName:           opts.Name,
+		addressTypes:       opts.AddressTypes,
+		apexAddressType:    opts.ApexAddressType,
+		selector:           opts.Selector,
+		recordNameTemplate: tmpl,
+		nodeRecords:        opts.NodeRecords,
+		groupByLabel:       opts.GroupByLabel,
+		topologyRecords:    opts.TopologyRecords,
+		latencyRouting:     opts.LatencyRouting,
+		weightedRecords:    opts.WeightedRecords,
+		eligibility:        c.eligibility,
+	})
+	z.sources = append(z.sources, nodes)
+	if opts.SyncServices {
+		if c.services == nil {
+			c.services = newServiceWatcher(c.client, c.log)
+		}
+		z.sources = append(z.sources, newServiceSource(c.services, c.log, opts.Name, nodes, serviceAddressType))
+	}
+	if opts.SyncIngresses {
+		if c.ingresses == nil {
+			c.ingresses = newIngressWatcher(c.client, c.log)
+		}
+		z.sources = append(z.sources, newIngressSource(c.ingresses, c.log, opts.Name, nodes, serviceAddressType))
+	}
+	return z, nil
+}
+
+// zoneFor returns the most specific zone containing name.
+func (c *Controller) zoneFor(name string) (*zone, error) {
+	var result *zone
+	for _, z := range c.zones {
+		if validateRecordName(name, z.name) != nil {
+			continue
+		}
+		if result == nil || len(z.name) > len(result.name) {
+			result = z
+		}
+	}
+	if result == nil {
+		return nil, fmt.Errorf("record name %q is not inside of any zone", name)
+	}
+	return result, nil
+}
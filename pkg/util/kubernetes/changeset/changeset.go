@@ -0,0 +1,23 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+// Package changeset extends the Kubernetes DNS Provider interfaces with
+// atomically applied change sets.
+package changeset
+
+import (
+	"k8s.io/kubernetes/federation/pkg/dnsprovider"
+)
+
+// Batcher is implemented by ResourceRecordSets of DNS Providers that can
+// apply several changes in a single atomic operation.
+type Batcher interface {
+	// Apply removes removals and adds additions atomically. Either all changes
+	// are applied or none of them. Removals are applied before additions, so a
+	// Resource Record Set can be replaced by removing and adding it.
+	Apply(removals, additions []dnsprovider.ResourceRecordSet) error
+}
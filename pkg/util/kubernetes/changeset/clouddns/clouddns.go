@@ -0,0 +1,147 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+// Package clouddns adds atomic change sets to the Kubernetes Google Cloud DNS Provider.
+package clouddns
+
+import (
+	"fmt"
+	"strconv"
+
+	dns "google.golang.org/api/dns/v1"
+
+	"k8s.io/kubernetes/federation/pkg/dnsprovider"
+
+	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/changeset"
+	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/routing"
+)
+
+var _ dnsprovider.Interface = new(Interface)
+var _ changeset.Batcher = new(ResourceRecordSets)
+
+// Interface wraps the Kubernetes Cloud DNS Provider, whose ResourceRecordSets
+// implement changeset.Batcher using Cloud DNS changes.
+type Interface struct {
+	*changeset.Interface
+}
+
+// Wrap returns provider with support for change sets. provider must be the
+// Kubernetes Cloud DNS Provider using the same project as service.
+func Wrap(provider dnsprovider.Interface, service *dns.Service, project string) *Interface {
+	wrap := func(zone *changeset.Zone, rrs dnsprovider.ResourceRecordSets) dnsprovider.ResourceRecordSets {
+		return &ResourceRecordSets{ResourceRecordSets: rrs, zone: zone, service: service, project: project}
+	}
+	return &Interface{Interface: changeset.Wrap(provider, wrap)}
+}
+
+// ResourceRecordSets wraps the ResourceRecordSets of the Kubernetes Cloud DNS Provider.
+type ResourceRecordSets struct {
+	dnsprovider.ResourceRecordSets
+	zone    *changeset.Zone
+	service *dns.Service
+	project string
+}
+
+// managedZoneID returns the name or id of the managed zone, see changeset.Zone.ResolveID.
+func (r *ResourceRecordSets) managedZoneID() (string, error) {
+	return r.zone.ResolveID(r.lookupManagedZoneID)
+}
+
+// lookupManagedZoneID looks up the id of the managed zone with the DNS name name.
+func (r *ResourceRecordSets) lookupManagedZoneID(name string) (string, error) {
+	out, err := r.service.ManagedZones.List(r.project).DnsName(name).Do()
+	if err != nil {
+		return "", err
+	}
+	switch len(out.ManagedZones) {
+	case 0:
+		return "", fmt.Errorf("managed zone %q not found", name)
+	case 1:
+		return strconv.FormatUint(out.ManagedZones[0].Id, 10), nil
+	}
+	return "", fmt.Errorf("managed zone %q is ambiguous, found %d managed zones with that name", name, len(out.ManagedZones))
+}
+
+// maxRecordSetsPerChange is the maximum number of additions and deletions of
+// a Cloud DNS change, staying below the quotas of additions and of deletions.
+const maxRecordSetsPerChange = 1000
+
+// Apply implements changeset.Batcher by sending the changes in a single Cloud DNS
+// change, which replaces Resource Record Sets without a gap. Change sets exceeding
+// maxRecordSetsPerChange are split into several changes, which are atomic each.
+// The deletion and addition replacing a Resource Record Set are always sent in
+// the same change.
+func (r *ResourceRecordSets) Apply(removals, additions []dnsprovider.ResourceRecordSet) error {
+	if len(removals) == 0 && len(additions) == 0 {
+		return nil
+	}
+	zoneID, err := r.managedZoneID()
+	if err != nil {
+		return err
+	}
+	for _, change := range splitChanges(removals, additions) {
+		_, err = r.service.Changes.Create(r.project, zoneID, change).Do()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitChanges converts removals and additions into Cloud DNS changes of at most
+// maxRecordSetsPerChange Resource Record Sets, keeping the deletion and addition
+// of a Resource Record Set together.
+func splitChanges(removals, additions []dnsprovider.ResourceRecordSet) []*dns.Change {
+	var keys []string
+	groups := map[string]*dns.Change{}
+	group := func(record dnsprovider.ResourceRecordSet) *dns.Change {
+		key := routing.Key(record)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+			groups[key] = &dns.Change{}
+		}
+		return groups[key]
+	}
+	for _, x := range removals {
+		g := group(x)
+		g.Deletions = append(g.Deletions, newRRSet(x))
+	}
+	for _, x := range additions {
+		g := group(x)
+		g.Additions = append(g.Additions, newRRSet(x))
+	}
+
+	var changes []*dns.Change
+	change := &dns.Change{}
+	for _, key := range keys {
+		g := groups[key]
+		if changeSize(change)+changeSize(g) > maxRecordSetsPerChange {
+			changes = append(changes, change)
+			change = &dns.Change{}
+		}
+		change.Deletions = append(change.Deletions, g.Deletions...)
+		change.Additions = append(change.Additions, g.Additions...)
+	}
+	return append(changes, change)
+}
+
+// changeSize returns the number of Resource Record Sets of change.
+func changeSize(change *dns.Change) int {
+	return len(change.Deletions) + len(change.Additions)
+}
+
+// newRRSet converts record into a Cloud DNS Resource Record Set. Deletions must
+// match the Resource Record Set at Cloud DNS, so record must be a listed one.
+func newRRSet(record dnsprovider.ResourceRecordSet) *dns.ResourceRecordSet {
+	return &dns.ResourceRecordSet{
+		Kind:    "dns#resourceRecordSet",
+		Name:    record.Name(),
+		Type:    string(record.Type()),
+		Ttl:     record.Ttl(),
+		Rrdatas: record.Rrdatas(),
+	}
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package clouddns
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	dns "google.golang.org/api/dns/v1"
+
+	"k8s.io/kubernetes/federation/pkg/dnsprovider"
+	"k8s.io/kubernetes/federation/pkg/dnsprovider/rrstype"
+
+	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/dnsproviderfake"
+)
+
+func newRecords(n int, data string) []dnsprovider.ResourceRecordSet {
+	var result []dnsprovider.ResourceRecordSet
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("node%d.example.com.", i)
+		result = append(result, &dnsproviderfake.ResourceRecordSetFake{RRSName: name, RRSDatas: []string{data}, RRSTTL: 60, RRSType: rrstype.A})
+	}
+	return result
+}
+
+func TestSplitChanges(t *testing.T) {
+	testScenarios := []struct {
+		removals  int
+		additions int
+		changes   []int
+	}{
+		{removals: 0, additions: 3, changes: []int{3}},
+		{removals: 0, additions: 1000, changes: []int{1000}},
+		{removals: 0, additions: 1001, changes: []int{1000, 1}},
+		// Replacements of 600 Records need 1200 Resource Record Sets, which are split between pairs.
+		{removals: 600, additions: 600, changes: []int{1000, 200}},
+	}
+	for _, x := range testScenarios {
+		changes := splitChanges(newRecords(x.removals, "1.1.1.1"), newRecords(x.additions, "2.2.2.2"))
+		if len(changes) != len(x.changes) {
+			t.Errorf("expected %d changes, got %d", len(x.changes), len(changes))
+			continue
+		}
+		for i, change := range changes {
+			if changeSize(change) != x.changes[i] {
+				t.Errorf("expected %d Resource Record Sets in change %d, got %d", x.changes[i], i, changeSize(change))
+			}
+			// A deletion must be sent together with the addition of the same Record.
+			additions := map[string]bool{}
+			for _, rrs := range change.Additions {
+				additions[rrs.Name] = true
+			}
+			for _, rrs := range change.Deletions {
+				if x.additions > 0 && !additions[rrs.Name] {
+					t.Errorf("replacement of %q was split", rrs.Name)
+				}
+			}
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	var changes []*dns.Change
+	lookups := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == "GET" && req.URL.Path == "/project/managedZones":
+			lookups++
+			if req.URL.Query().Get("dnsName") != "example.com." {
+				fmt.Fprint(w, `{"managedZones":[]}`)
+				return
+			}
+			fmt.Fprint(w, `{"managedZones":[{"id":"42","name":"example","dnsName":"example.com."}]}`)
+		case req.Method == "POST" && req.URL.Path == "/project/managedZones/42/changes":
+			change := &dns.Change{}
+			if err := json.NewDecoder(req.Body).Decode(change); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			changes = append(changes, change)
+			json.NewEncoder(w).Encode(change)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+
+	service, err := dns.New(http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.BasePath = server.URL + "/"
+	zones, _ := Wrap(&dnsproviderfake.Fake{}, service, "project").Zones()
+	zone, err := zones.New("example.com.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rrs, _ := zone.ResourceRecordSets()
+	batcher := rrs.(*ResourceRecordSets)
+
+	removals := newRecords(1, "1.1.1.1")
+	additions := append(newRecords(1, "2.2.2.2"), newRecords(2, "3.3.3.3")[1])
+	if err := batcher.Apply(removals, additions); err != nil {
+		t.Fatal(err)
+	}
+	if err := batcher.Apply(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := batcher.Apply(nil, newRecords(1, "4.4.4.4")); err != nil {
+		t.Fatal(err)
+	}
+
+	if lookups != 1 {
+		t.Errorf("expected the managed zone to be looked up once, got %d lookups", lookups)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d", len(changes))
+	}
+	expect := &dns.Change{
+		Deletions: []*dns.ResourceRecordSet{
+			{Kind: "dns#resourceRecordSet", Name: "node0.example.com.", Type: "A", Ttl: 60, Rrdatas: []string{"1.1.1.1"}},
+		},
+		Additions: []*dns.ResourceRecordSet{
+			{Kind: "dns#resourceRecordSet", Name: "node0.example.com.", Type: "A", Ttl: 60, Rrdatas: []string{"2.2.2.2"}},
+			{Kind: "dns#resourceRecordSet", Name: "node1.example.com.", Type: "A", Ttl: 60, Rrdatas: []string{"3.3.3.3"}},
+		},
+	}
+	if !reflect.DeepEqual(changes[0].Deletions, expect.Deletions) || !reflect.DeepEqual(changes[0].Additions, expect.Additions) {
+		t.Errorf("expected change %#v, got %#v", expect, changes[0])
+	}
+}
@@ -0,0 +1,381 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+// Package route53 adds atomic change sets, latency based and weighted routing
+// and health checks to the Kubernetes AWS Route53 DNS Provider.
+package route53
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsroute53 "github.com/aws/aws-sdk-go/service/route53"
+
+	"k8s.io/kubernetes/federation/pkg/dnsprovider"
+	"k8s.io/kubernetes/federation/pkg/dnsprovider/rrstype"
+
+	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/changeset"
+	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/routing"
+)
+
+var _ dnsprovider.Interface = new(Interface)
+var _ routing.HealthChecker = new(Interface)
+var _ changeset.Batcher = new(ResourceRecordSets)
+var _ routing.Router = new(ResourceRecordSets)
+var _ routing.Record = new(Record)
+
+// Interface wraps the Kubernetes Route53 DNS Provider, whose ResourceRecordSets
+// implement changeset.Batcher using Route53 change batches and routing.Router
+// using latency based and weighted routing. It implements routing.HealthChecker
+// using Route53 health checks.
+type Interface struct {
+	*changeset.Interface
+	service *awsroute53.Route53
+}
+
+// Wrap returns provider with support for change sets. provider must be the
+// Kubernetes Route53 DNS Provider using the same account as service.
+func Wrap(provider dnsprovider.Interface, service *awsroute53.Route53) *Interface {
+	wrap := func(zone *changeset.Zone, rrs dnsprovider.ResourceRecordSets) dnsprovider.ResourceRecordSets {
+		return &ResourceRecordSets{ResourceRecordSets: rrs, zone: zone, service: service}
+	}
+	return &Interface{Interface: changeset.Wrap(provider, wrap), service: service}
+}
+
+// healthCheckOwnerTag is the tag of the health checks holding the id of their owner.
+const healthCheckOwnerTag = "kube-dns-sync/owner"
+
+// maxTaggedResources is the maximum number of resources of a ListTagsForResources request.
+const maxTaggedResources = 10
+
+// HealthChecks implements routing.HealthChecker by listing the TCP health checks
+// tagged with owner.
+func (i *Interface) HealthChecks(owner string) (map[routing.HealthCheckTarget]string, error) {
+	checks := map[string]*awsroute53.HealthCheckConfig{}
+	var ids []*string
+	err := i.service.ListHealthChecksPages(&awsroute53.ListHealthChecksInput{}, func(page *awsroute53.ListHealthChecksOutput, lastPage bool) bool {
+		for _, x := range page.HealthChecks {
+			config := x.HealthCheckConfig
+			if config == nil || aws.StringValue(config.Type) != awsroute53.HealthCheckTypeTcp || config.IPAddress == nil {
+				continue
+			}
+			checks[aws.StringValue(x.Id)] = config
+			ids = append(ids, x.Id)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[routing.HealthCheckTarget]string{}
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > maxTaggedResources {
+			n = maxTaggedResources
+		}
+		out, err := i.service.ListTagsForResources(&awsroute53.ListTagsForResourcesInput{
+			ResourceType: aws.String(awsroute53.TagResourceTypeHealthcheck),
+			ResourceIds:  ids[:n],
+		})
+		if err != nil {
+			return nil, err
+		}
+		ids = ids[n:]
+		for _, set := range out.ResourceTagSets {
+			if !hasTag(set.Tags, healthCheckOwnerTag, owner) {
+				continue
+			}
+			id := aws.StringValue(set.ResourceId)
+			config := checks[id]
+			target := routing.HealthCheckTarget{
+				Address: aws.StringValue(config.IPAddress),
+				Port:    int(aws.Int64Value(config.Port)),
+			}
+			result[target] = id
+		}
+	}
+	return result, nil
+}
+
+// AddHealthCheck implements routing.HealthChecker by creating a TCP health check
+// tagged with owner.
+func (i *Interface) AddHealthCheck(owner string, target routing.HealthCheckTarget) (string, error) {
+	out, err := i.service.CreateHealthCheck(&awsroute53.CreateHealthCheckInput{
+		CallerReference: aws.String(fmt.Sprintf("kube-dns-sync-%d", time.Now().UnixNano())),
+		HealthCheckConfig: &awsroute53.HealthCheckConfig{
+			Type:      aws.String(awsroute53.HealthCheckTypeTcp),
+			IPAddress: aws.String(target.Address),
+			Port:      aws.Int64(int64(target.Port)),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	id := aws.StringValue(out.HealthCheck.Id)
+	_, err = i.service.ChangeTagsForResource(&awsroute53.ChangeTagsForResourceInput{
+		ResourceType: aws.String(awsroute53.TagResourceTypeHealthcheck),
+		ResourceId:   aws.String(id),
+		AddTags: []*awsroute53.Tag{
+			{Key: aws.String(healthCheckOwnerTag), Value: aws.String(owner)},
+			{Key: aws.String("Name"), Value: aws.String(fmt.Sprintf("%s:%d", target.Address, target.Port))},
+		},
+	})
+	if err != nil {
+		// An untagged health check would never be found and removed again.
+		if removeErr := i.RemoveHealthCheck(id); removeErr != nil {
+			return "", fmt.Errorf("failed to tag health check %q: %v, and to remove it: %v", id, err, removeErr)
+		}
+		return "", err
+	}
+	return id, nil
+}
+
+// RemoveHealthCheck implements routing.HealthChecker.
+func (i *Interface) RemoveHealthCheck(id string) error {
+	_, err := i.service.DeleteHealthCheck(&awsroute53.DeleteHealthCheckInput{HealthCheckId: aws.String(id)})
+	return err
+}
+
+// hasTag returns true when tags contain key with value.
+func hasTag(tags []*awsroute53.Tag, key, value string) bool {
+	for _, x := range tags {
+		if aws.StringValue(x.Key) == key && aws.StringValue(x.Value) == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ResourceRecordSets wraps the ResourceRecordSets of the Kubernetes Route53 DNS Provider.
+type ResourceRecordSets struct {
+	dnsprovider.ResourceRecordSets
+	zone    *changeset.Zone
+	service *awsroute53.Route53
+}
+
+// hostedZoneID returns the id of the hosted zone, see changeset.Zone.ResolveID.
+func (r *ResourceRecordSets) hostedZoneID() (string, error) {
+	return r.zone.ResolveID(r.lookupHostedZoneID)
+}
+
+// lookupHostedZoneID looks up the id of the hosted zone name. The lookup fails
+// when several hosted zones share the name, like a public and a private zone,
+// because the wrapped Zone can't be told apart from the others.
+func (r *ResourceRecordSets) lookupHostedZoneID(name string) (string, error) {
+	out, err := r.service.ListHostedZonesByName(&awsroute53.ListHostedZonesByNameInput{
+		DNSName: aws.String(name),
+	})
+	if err != nil {
+		return "", err
+	}
+	var ids []string
+	for _, x := range out.HostedZones {
+		if aws.StringValue(x.Name) == name {
+			ids = append(ids, aws.StringValue(x.Id))
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("hosted zone %q not found", name)
+	case 1:
+		return ids[0], nil
+	}
+	return "", fmt.Errorf("hosted zone %q is ambiguous, found %d hosted zones with that name", name, len(ids))
+}
+
+// List returns the Resource Record Sets of the zone including their routing policies,
+// which are missing from the Resource Record Sets of the Kubernetes Route53 DNS Provider.
+func (r *ResourceRecordSets) List() ([]dnsprovider.ResourceRecordSet, error) {
+	zoneID, err := r.hostedZoneID()
+	if err != nil {
+		return nil, err
+	}
+	var result []dnsprovider.ResourceRecordSet
+	input := &awsroute53.ListResourceRecordSetsInput{HostedZoneId: aws.String(zoneID)}
+	err = r.service.ListResourceRecordSetsPages(input, func(page *awsroute53.ListResourceRecordSetsOutput, lastPage bool) bool {
+		for _, x := range page.ResourceRecordSets {
+			result = append(result, newRecord(x))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// NewRecord implements routing.Router.
+func (r *ResourceRecordSets) NewRecord(name string, rrdatas []string, ttl int64, rrstype rrstype.RrsType, policy routing.Policy) routing.Record {
+	return &Record{name: name, rrdatas: rrdatas, ttl: ttl, rrstype: rrstype, policy: policy}
+}
+
+// maxChangesPerBatch is the maximum number of changes of a Route53 change batch.
+const maxChangesPerBatch = 1000
+
+// Apply implements changeset.Batcher by sending the changes in a single change
+// batch. Change sets exceeding maxChangesPerBatch are split into several batches,
+// which are atomic each. The removal and addition replacing a Resource Record Set
+// are always sent in the same batch.
+func (r *ResourceRecordSets) Apply(removals, additions []dnsprovider.ResourceRecordSet) error {
+	if len(removals) == 0 && len(additions) == 0 {
+		return nil
+	}
+	zoneID, err := r.hostedZoneID()
+	if err != nil {
+		return err
+	}
+	for _, changes := range splitBatches(removals, additions) {
+		_, err = r.service.ChangeResourceRecordSets(&awsroute53.ChangeResourceRecordSetsInput{
+			HostedZoneId: aws.String(zoneID),
+			ChangeBatch:  &awsroute53.ChangeBatch{Changes: changes},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitBatches converts removals and additions into change batches of at most
+// maxChangesPerBatch changes, keeping the changes of a Resource Record Set together.
+func splitBatches(removals, additions []dnsprovider.ResourceRecordSet) [][]*awsroute53.Change {
+	var keys []string
+	groups := map[string][]*awsroute53.Change{}
+	add := func(action string, record dnsprovider.ResourceRecordSet) {
+		key := routing.Key(record)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], newChange(action, record))
+	}
+	for _, x := range removals {
+		add(awsroute53.ChangeActionDelete, x)
+	}
+	for _, x := range additions {
+		add(awsroute53.ChangeActionCreate, x)
+	}
+
+	var batches [][]*awsroute53.Change
+	var batch []*awsroute53.Change
+	for _, key := range keys {
+		if len(batch)+len(groups[key]) > maxChangesPerBatch {
+			batches = append(batches, batch)
+			batch = nil
+		}
+		batch = append(batch, groups[key]...)
+	}
+	return append(batches, batch)
+}
+
+// newChange converts record into a Route53 change with action.
+func newChange(action string, record dnsprovider.ResourceRecordSet) *awsroute53.Change {
+	var records []*awsroute53.ResourceRecord
+	for _, x := range record.Rrdatas() {
+		records = append(records, &awsroute53.ResourceRecord{Value: aws.String(x)})
+	}
+	rrs := &awsroute53.ResourceRecordSet{
+		Name:            aws.String(record.Name()),
+		Type:            aws.String(string(record.Type())),
+		TTL:             aws.Int64(record.Ttl()),
+		ResourceRecords: records,
+	}
+	if policy := routing.PolicyOf(record); policy.SetIdentifier != "" {
+		rrs.SetIdentifier = aws.String(policy.SetIdentifier)
+		if policy.Region != "" {
+			rrs.Region = aws.String(policy.Region)
+		}
+		if policy.Weighted {
+			rrs.Weight = aws.Int64(policy.Weight)
+		}
+		if policy.HealthCheckID != "" {
+			rrs.HealthCheckId = aws.String(policy.HealthCheckID)
+		}
+	}
+	return &awsroute53.Change{
+		Action:            aws.String(action),
+		ResourceRecordSet: rrs,
+	}
+}
+
+// Record is a Route53 Resource Record Set with its routing policy.
+type Record struct {
+	name    string
+	rrdatas []string
+	ttl     int64
+	rrstype rrstype.RrsType
+	policy  routing.Policy
+}
+
+// newRecord converts a listed Route53 Resource Record Set into a Record.
+func newRecord(rrs *awsroute53.ResourceRecordSet) *Record {
+	var rrdatas []string
+	for _, x := range rrs.ResourceRecords {
+		rrdatas = append(rrdatas, aws.StringValue(x.Value))
+	}
+	return &Record{
+		name:    unescapeName(aws.StringValue(rrs.Name)),
+		rrdatas: rrdatas,
+		ttl:     aws.Int64Value(rrs.TTL),
+		rrstype: rrstype.RrsType(aws.StringValue(rrs.Type)),
+		policy: routing.Policy{
+			SetIdentifier: aws.StringValue(rrs.SetIdentifier),
+			Region:        aws.StringValue(rrs.Region),
+			Weighted:      rrs.Weight != nil,
+			Weight:        aws.Int64Value(rrs.Weight),
+			HealthCheckID: aws.StringValue(rrs.HealthCheckId),
+		},
+	}
+}
+
+// unescapeName reverts the octal escapes of names listed by Route53, like "\052"
+// for the "*" of wildcard Records, so that they match the names that were created.
+func unescapeName(name string) string {
+	if !strings.Contains(name, "\\") {
+		return name
+	}
+	var result []byte
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+3 < len(name) {
+			if code, err := strconv.ParseUint(name[i+1:i+4], 8, 8); err == nil {
+				result = append(result, byte(code))
+				i += 3
+				continue
+			}
+		}
+		result = append(result, name[i])
+	}
+	return string(result)
+}
+
+// Name returns the name of the Record.
+func (r *Record) Name() string {
+	return r.name
+}
+
+// Rrdatas returns the values of the Record.
+func (r *Record) Rrdatas() []string {
+	return r.rrdatas
+}
+
+// Ttl returns the TTL of the Record.
+func (r *Record) Ttl() int64 {
+	return r.ttl
+}
+
+// Type returns the type of the Record.
+func (r *Record) Type() rrstype.RrsType {
+	return r.rrstype
+}
+
+// Policy implements routing.Record.
+func (r *Record) Policy() routing.Policy {
+	return r.policy
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package route53
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+
+	"k8s.io/kubernetes/federation/pkg/dnsprovider"
+	"k8s.io/kubernetes/federation/pkg/dnsprovider/rrstype"
+)
+
+func TestSplitBatches(t *testing.T) {
+	newRecords := func(n int, data string) []dnsprovider.ResourceRecordSet {
+		var result []dnsprovider.ResourceRecordSet
+		for i := 0; i < n; i++ {
+			name := fmt.Sprintf("node%d.example.com.", i)
+			result = append(result, &Record{name: name, rrdatas: []string{data}, ttl: 60, rrstype: rrstype.A})
+		}
+		return result
+	}
+
+	testScenarios := []struct {
+		removals  int
+		additions int
+		batches   []int
+	}{
+		{removals: 0, additions: 3, batches: []int{3}},
+		{removals: 0, additions: 1000, batches: []int{1000}},
+		{removals: 0, additions: 1001, batches: []int{1000, 1}},
+		// Replacements of 600 Records need 1200 changes, which are split between pairs.
+		{removals: 600, additions: 600, batches: []int{1000, 200}},
+	}
+	for _, x := range testScenarios {
+		batches := splitBatches(newRecords(x.removals, "1.1.1.1"), newRecords(x.additions, "2.2.2.2"))
+		if len(batches) != len(x.batches) {
+			t.Errorf("expected %d batches, got %d", len(x.batches), len(batches))
+			continue
+		}
+		for i, batch := range batches {
+			if len(batch) != x.batches[i] {
+				t.Errorf("expected %d changes in batch %d, got %d", x.batches[i], i, len(batch))
+			}
+			// A removal must be followed by the addition of the same Record.
+			for j, change := range batch {
+				if aws.StringValue(change.Action) != "DELETE" {
+					continue
+				}
+				name := aws.StringValue(change.ResourceRecordSet.Name)
+				if x.additions > 0 && (j+1 >= len(batch) || aws.StringValue(batch[j+1].ResourceRecordSet.Name) != name) {
+					t.Errorf("replacement of %q was split", name)
+				}
+			}
+		}
+	}
+}
+
+func TestUnescapeName(t *testing.T) {
+	testScenarios := []struct {
+		name   string
+		expect string
+	}{
+		{name: "www.example.com.", expect: "www.example.com."},
+		{name: "\\052.example.com.", expect: "*.example.com."},
+		{name: "_kube-dns-sync.\\052.example.com.", expect: "_kube-dns-sync.*.example.com."},
+		{name: "a\\100b.example.com.", expect: "a@b.example.com."},
+		{name: "trailing\\05", expect: "trailing\\05"},
+		{name: "invalid\\9xy.example.com.", expect: "invalid\\9xy.example.com."},
+	}
+	for _, x := range testScenarios {
+		if got := unescapeName(x.name); got != x.expect {
+			t.Errorf("expected %q to be unescaped to %q, got %q", x.name, x.expect, got)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package changeset
+
+import (
+	"k8s.io/kubernetes/federation/pkg/dnsprovider"
+)
+
+var _ dnsprovider.Interface = new(Interface)
+var _ dnsprovider.Zones = new(Zones)
+var _ dnsprovider.Zone = new(Zone)
+
+// WrapFunc wraps the ResourceRecordSets rrs of zone, e.g. to implement Batcher
+// for a specific DNS Provider.
+type WrapFunc func(zone *Zone, rrs dnsprovider.ResourceRecordSets) dnsprovider.ResourceRecordSets
+
+// Interface wraps a Kubernetes DNS Provider, whose ResourceRecordSets are
+// wrapped by a WrapFunc. The Zones are wrapped in turn, so that Zones returned
+// by List, Add and New provide the wrapped ResourceRecordSets.
+type Interface struct {
+	dnsprovider.Interface
+	wrap WrapFunc
+}
+
+// Wrap returns provider with its ResourceRecordSets wrapped by wrap.
+func Wrap(provider dnsprovider.Interface, wrap WrapFunc) *Interface {
+	return &Interface{Interface: provider, wrap: wrap}
+}
+
+// Zones returns the wrapped Zones.
+func (i *Interface) Zones() (dnsprovider.Zones, bool) {
+	zones, supported := i.Interface.Zones()
+	if !supported {
+		return nil, false
+	}
+	return &Zones{Zones: zones, wrapRRS: i.wrap}, true
+}
+
+// Zones wraps the Zones of a Kubernetes DNS Provider.
+type Zones struct {
+	dnsprovider.Zones
+	wrapRRS WrapFunc
+}
+
+// List returns the wrapped Zones.
+func (z *Zones) List() ([]dnsprovider.Zone, error) {
+	list, err := z.Zones.List()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dnsprovider.Zone, 0, len(list))
+	for _, x := range list {
+		result = append(result, z.wrap(x))
+	}
+	return result, nil
+}
+
+// Add adds zone and returns it wrapped.
+func (z *Zones) Add(zone dnsprovider.Zone) (dnsprovider.Zone, error) {
+	added, err := z.Zones.Add(unwrap(zone))
+	if err != nil {
+		return nil, err
+	}
+	return z.wrap(added), nil
+}
+
+// Remove removes zone.
+func (z *Zones) Remove(zone dnsprovider.Zone) error {
+	return z.Zones.Remove(unwrap(zone))
+}
+
+// New returns a new wrapped Zone.
+func (z *Zones) New(name string) (dnsprovider.Zone, error) {
+	zone, err := z.Zones.New(name)
+	if err != nil {
+		return nil, err
+	}
+	return z.wrap(zone), nil
+}
+
+func (z *Zones) wrap(zone dnsprovider.Zone) dnsprovider.Zone {
+	return &Zone{Zone: zone, wrapRRS: z.wrapRRS}
+}
+
+func unwrap(zone dnsprovider.Zone) dnsprovider.Zone {
+	if z, ok := zone.(*Zone); ok {
+		return z.Zone
+	}
+	return zone
+}
+
+// Zone wraps a Zone of a Kubernetes DNS Provider.
+type Zone struct {
+	dnsprovider.Zone
+	wrapRRS WrapFunc
+
+	// id of the zone at the DNS Provider, resolved by ResolveID.
+	id string
+}
+
+// ResourceRecordSets returns the wrapped ResourceRecordSets.
+func (z *Zone) ResourceRecordSets() (dnsprovider.ResourceRecordSets, bool) {
+	rrs, supported := z.Zone.ResourceRecordSets()
+	if !supported {
+		return nil, false
+	}
+	return z.wrapRRS(z, rrs), true
+}
+
+// ResolveID returns the id of the zone at the DNS Provider. It is taken from the
+// wrapped Zone when it exposes its id, otherwise it is looked up by the name of
+// the zone using lookup once.
+func (z *Zone) ResolveID(lookup func(name string) (string, error)) (string, error) {
+	if z.id != "" {
+		return z.id, nil
+	}
+	if x, ok := z.Zone.(interface {
+		ID() string
+	}); ok {
+		z.id = x.ID()
+		return z.id, nil
+	}
+	id, err := lookup(z.Name())
+	if err != nil {
+		return "", err
+	}
+	z.id = id
+	return z.id, nil
+}
@@ -12,17 +12,27 @@ import (
 
 	"k8s.io/kubernetes/federation/pkg/dnsprovider"
 	"k8s.io/kubernetes/federation/pkg/dnsprovider/rrstype"
+
+	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/changeset"
+	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/routing"
+	netutil "github.com/wikiwi/kube-dns-sync/pkg/util/net"
 )
 
 var _ dnsprovider.Interface = new(Fake)
+var _ routing.HealthChecker = new(Fake)
 var _ dnsprovider.Zones = new(ZonesFake)
 var _ dnsprovider.Zone = new(ZoneFake)
 var _ dnsprovider.ResourceRecordSets = new(ResourceRecordSetsFake)
-var _ dnsprovider.ResourceRecordSet = new(ResourceRecordSetFake)
+var _ changeset.Batcher = new(ResourceRecordSetsFake)
+var _ routing.Router = new(ResourceRecordSetsFake)
+var _ routing.Record = new(ResourceRecordSetFake)
 
 // Fake is a fake dns provider.
 type Fake struct {
-	ZonesFake ZonesFake
+	ZonesFake       ZonesFake
+	HealthCheckList []HealthCheckFake
+
+	lastHealthCheckID int
 }
 
 // Zones returns ZonesFake.
@@ -30,6 +40,43 @@ func (f *Fake) Zones() (dnsprovider.Zones, bool) {
 	return &f.ZonesFake, true
 }
 
+// HealthCheckFake is a fake health check.
+type HealthCheckFake struct {
+	ID     string
+	Owner  string
+	Target routing.HealthCheckTarget
+}
+
+// HealthChecks returns the ids of the health checks of owner by their targets.
+func (f *Fake) HealthChecks(owner string) (map[routing.HealthCheckTarget]string, error) {
+	result := map[routing.HealthCheckTarget]string{}
+	for _, x := range f.HealthCheckList {
+		if x.Owner == owner {
+			result[x.Target] = x.ID
+		}
+	}
+	return result, nil
+}
+
+// AddHealthCheck adds a health check of target to list and returns its id.
+func (f *Fake) AddHealthCheck(owner string, target routing.HealthCheckTarget) (string, error) {
+	f.lastHealthCheckID++
+	id := fmt.Sprintf("hc-%d", f.lastHealthCheckID)
+	f.HealthCheckList = append(f.HealthCheckList, HealthCheckFake{ID: id, Owner: owner, Target: target})
+	return id, nil
+}
+
+// RemoveHealthCheck removes the health check with id from list.
+func (f *Fake) RemoveHealthCheck(id string) error {
+	for i, x := range f.HealthCheckList {
+		if x.ID == id {
+			f.HealthCheckList = append(f.HealthCheckList[:i], f.HealthCheckList[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("health check %q not found", id)
+}
+
 // ZonesFake is a fake of Zones.
 type ZonesFake struct {
 	ZoneList []dnsprovider.Zone
@@ -81,28 +128,69 @@ func (f *ZoneFake) ResourceRecordSets() (dnsprovider.ResourceRecordSets, bool) {
 // ResourceRecordSetsFake fake implementation of ResourceRecordSets.
 type ResourceRecordSetsFake struct {
 	RRSList []dnsprovider.ResourceRecordSet
+
+	// AddHook is called before adding a Resource Record Set when set,
+	// a returned error fails the Add.
+	AddHook func(rrs dnsprovider.ResourceRecordSet) error
 }
 
-// List returns list of Resource Record Sets.
+// List returns a copy of the list of Resource Record Sets.
 func (f *ResourceRecordSetsFake) List() ([]dnsprovider.ResourceRecordSet, error) {
-	return f.RRSList, nil
+	return append([]dnsprovider.ResourceRecordSet{}, f.RRSList...), nil
 }
 
-// Add Resource Record Set to list.
+// Add Resource Record Set to list. Like real providers it refuses to add
+// a second Resource Record Set with the same name, type and set identifier,
+// and A or AAAA Records with addresses of the wrong family.
 func (f *ResourceRecordSetsFake) Add(rrs dnsprovider.ResourceRecordSet) (dnsprovider.ResourceRecordSet, error) {
+	if f.AddHook != nil {
+		if err := f.AddHook(rrs); err != nil {
+			return nil, err
+		}
+	}
+	if rrs.Type() == rrstype.A || rrs.Type() == rrstype.AAAA {
+		for _, x := range rrs.Rrdatas() {
+			if t, err := netutil.RecordType(x); err != nil || t != rrs.Type() {
+				return nil, fmt.Errorf("invalid %s Record data %q", rrs.Type(), x)
+			}
+		}
+	}
+	for _, x := range f.RRSList {
+		if routing.Key(rrs) == routing.Key(x) {
+			return nil, fmt.Errorf("Resource Record Set %q of type %q already exists", rrs.Name(), rrs.Type())
+		}
+	}
 	f.RRSList = append(f.RRSList, rrs)
 	return rrs, nil
 }
 
-// Remove Resource Record Set from list.
+// Remove Resource Record Set with matching name, type and set identifier from list.
 func (f *ResourceRecordSetsFake) Remove(rrs dnsprovider.ResourceRecordSet) error {
 	for i, x := range f.RRSList {
-		if rrs.Name() == x.Name() {
+		if routing.Key(rrs) == routing.Key(x) {
 			f.RRSList = append(f.RRSList[:i], f.RRSList[i+1:]...)
 			return nil
 		}
 	}
-	return fmt.Errorf("Resource Record Set %q not found", rrs.Name())
+	return fmt.Errorf("Resource Record Set %q of type %q not found", rrs.Name(), rrs.Type())
+}
+
+// Apply removes removals and adds additions atomically. The list is left
+// untouched when one of the changes fails.
+func (f *ResourceRecordSetsFake) Apply(removals, additions []dnsprovider.ResourceRecordSet) error {
+	tmp := &ResourceRecordSetsFake{RRSList: append([]dnsprovider.ResourceRecordSet{}, f.RRSList...), AddHook: f.AddHook}
+	for _, x := range removals {
+		if err := tmp.Remove(x); err != nil {
+			return err
+		}
+	}
+	for _, x := range additions {
+		if _, err := tmp.Add(x); err != nil {
+			return err
+		}
+	}
+	f.RRSList = tmp.RRSList
+	return nil
 }
 
 // New creates instance of ResourceRecordSetFake.
@@ -112,12 +200,25 @@ func (f *ResourceRecordSetsFake) New(name string, rrdatas []string, ttl int64, r
 	}
 }
 
+// NewRecord creates instance of ResourceRecordSetFake with a routing policy.
+func (f *ResourceRecordSetsFake) NewRecord(name string, rrdatas []string, ttl int64, rrstype rrstype.RrsType, policy routing.Policy) routing.Record {
+	return &ResourceRecordSetFake{
+		RRSName: name, RRSDatas: rrdatas, RRSTTL: ttl, RRSType: rrstype, RRSPolicy: policy,
+	}
+}
+
 // ResourceRecordSetFake is a fake implementation of ResourceRecordSet.
 type ResourceRecordSetFake struct {
-	RRSName  string
-	RRSDatas []string
-	RRSTTL   int64
-	RRSType  rrstype.RrsType
+	RRSName   string
+	RRSDatas  []string
+	RRSTTL    int64
+	RRSType   rrstype.RrsType
+	RRSPolicy routing.Policy
+}
+
+// Policy returns the routing policy of Resource Record Set.
+func (f *ResourceRecordSetFake) Policy() routing.Policy {
+	return f.RRSPolicy
 }
 
 // Name returns name of Resource Record Set.
@@ -0,0 +1,85 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+// Package routing extends the Kubernetes DNS Provider interfaces with routing
+// policies, which select one of several Resource Record Sets sharing a name and type,
+// and with health checks, which drop Resource Record Sets of unhealthy targets.
+package routing
+
+import (
+	"k8s.io/kubernetes/federation/pkg/dnsprovider"
+	"k8s.io/kubernetes/federation/pkg/dnsprovider/rrstype"
+)
+
+// Policy is the routing policy of a Resource Record Set.
+type Policy struct {
+	// SetIdentifier distinguishes the Resource Record Sets sharing a name and type.
+	SetIdentifier string
+
+	// Region enables latency based routing, the Resource Record Set is
+	// returned to resolvers with the lowest latency to Region, like "us-east-1".
+	Region string
+
+	// Weighted enables weighted routing, the Resource Record Set is returned
+	// in proportion of Weight to the sum of the weights of all Resource Record Sets.
+	Weighted bool
+	Weight   int64
+
+	// HealthCheckID binds the Resource Record Set to a health check, it is
+	// not returned while the health check fails.
+	HealthCheckID string
+}
+
+// HealthCheckTarget is the endpoint probed by a health check.
+type HealthCheckTarget struct {
+	// Address is the IP address, like "1.1.1.1".
+	Address string
+
+	// Port is the TCP port, like 443.
+	Port int
+}
+
+// HealthChecker is implemented by DNS Providers supporting health checks,
+// which are tagged with the id of their owner to tell them apart from others.
+type HealthChecker interface {
+	// HealthChecks returns the ids of the health checks of owner by their targets.
+	HealthChecks(owner string) (map[HealthCheckTarget]string, error)
+
+	// AddHealthCheck creates a TCP health check of target for owner and returns its id.
+	AddHealthCheck(owner string, target HealthCheckTarget) (string, error)
+
+	// RemoveHealthCheck removes the health check with id.
+	RemoveHealthCheck(id string) error
+}
+
+// Record is a Resource Record Set with a routing Policy.
+type Record interface {
+	dnsprovider.ResourceRecordSet
+
+	// Policy returns the routing policy, which is empty for Resource Record Sets without one.
+	Policy() Policy
+}
+
+// Router is implemented by ResourceRecordSets of DNS Providers supporting
+// routing policies. Their List returns Records including the routing policies.
+type Router interface {
+	// NewRecord creates a Resource Record Set with a routing policy.
+	NewRecord(name string, rrdatas []string, ttl int64, rrstype rrstype.RrsType, policy Policy) Record
+}
+
+// PolicyOf returns the routing policy of rrs.
+func PolicyOf(rrs dnsprovider.ResourceRecordSet) Policy {
+	if r, ok := rrs.(Record); ok {
+		return r.Policy()
+	}
+	return Policy{}
+}
+
+// Key identifies a Resource Record Set by its type, name and set identifier.
+func Key(rrs dnsprovider.ResourceRecordSet) string {
+	return string(rrs.Type()) + " " + rrs.Name() + " " + PolicyOf(rrs).SetIdentifier
+}
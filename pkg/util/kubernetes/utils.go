@@ -14,9 +14,13 @@ import (
 	"strings"
 
 	"k8s.io/kubernetes/federation/pkg/dnsprovider"
+	"k8s.io/kubernetes/federation/pkg/dnsprovider/rrstype"
 	"k8s.io/kubernetes/pkg/api"
 	"k8s.io/kubernetes/pkg/client/unversioned"
 	"k8s.io/kubernetes/pkg/client/unversioned/clientcmd"
+
+	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/routing"
+	netutil "github.com/wikiwi/kube-dns-sync/pkg/util/net"
 )
 
 // NewKubeClient creates a new Unversioned Kubernetes Client using default loading rules.
@@ -43,6 +47,7 @@ func EqualRRSList(a []dnsprovider.ResourceRecordSet, b []dnsprovider.ResourceRec
 	if len(a) != len(b) {
 		return false
 	}
+	b = append([]dnsprovider.ResourceRecordSet{}, b...)
 	for _, x := range a {
 		found := false
 		for i, y := range b {
@@ -70,13 +75,29 @@ func EqualRRS(a dnsprovider.ResourceRecordSet, b dnsprovider.ResourceRecordSet)
 	if a.Type() != b.Type() {
 		return false
 	}
-	dataA := sort.StringSlice(a.Rrdatas())
+	if routing.PolicyOf(a) != routing.PolicyOf(b) {
+		return false
+	}
+	dataA := normalizedRrdatas(a)
 	dataA.Sort()
-	dataB := sort.StringSlice(b.Rrdatas())
+	dataB := normalizedRrdatas(b)
 	dataB.Sort()
 	return reflect.DeepEqual(dataA, dataB)
 }
 
+// normalizedRrdatas returns the datas of rrs, IP addresses of A and AAAA
+// Records are converted to their canonical representation.
+func normalizedRrdatas(rrs dnsprovider.ResourceRecordSet) sort.StringSlice {
+	datas := sort.StringSlice{}
+	for _, x := range rrs.Rrdatas() {
+		if rrs.Type() == rrstype.A || rrs.Type() == rrstype.AAAA {
+			x = netutil.NormalizeAddress(x)
+		}
+		datas = append(datas, x)
+	}
+	return datas
+}
+
 // IsNodeReady checks for the NodeReady condition in the Node.
 func IsNodeReady(node *api.Node) bool {
 	for _, cond := range node.Status.Conditions {
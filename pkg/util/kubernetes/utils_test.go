@@ -60,6 +60,21 @@ func TestEqualRRS(t *testing.T) {
 			Bname: "name", Bttl: 300, BrrsType: rrstype.A, Bdata: []string{"b", "a"},
 			equal: true,
 		},
+		{
+			Aname: "name", Attl: 300, ArrsType: rrstype.AAAA, Adata: []string{"2001:db8::1", "2001:db8::2"},
+			Bname: "name", Bttl: 300, BrrsType: rrstype.AAAA, Bdata: []string{"2001:0db8::0002", "2001:db8::1"},
+			equal: true,
+		},
+		{
+			Aname: "name", Attl: 300, ArrsType: rrstype.AAAA, Adata: []string{"2001:db8::1"},
+			Bname: "name", Bttl: 300, BrrsType: rrstype.A, Bdata: []string{"2001:db8::1"},
+			equal: false,
+		},
+		{
+			Aname: "name", Attl: 300, ArrsType: rrstype.AAAA, Adata: []string{"2001:db8::1"},
+			Bname: "name", Bttl: 300, BrrsType: rrstype.AAAA, Bdata: []string{"2001:db8::2"},
+			equal: false,
+		},
 	}
 	for _, x := range testScenarios {
 		t.Log(pretty.Sprint(x))
@@ -0,0 +1,39 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+// Package net contains tools for dealing with IP addresses.
+package net
+
+import (
+	"fmt"
+	"net"
+
+	"k8s.io/kubernetes/federation/pkg/dnsprovider/rrstype"
+)
+
+// RecordType returns the DNS Record type for address, which is A for
+// IPv4 and AAAA for IPv6 addresses.
+func RecordType(address string) (rrstype.RrsType, error) {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return "", fmt.Errorf("invalid IP address %q", address)
+	}
+	if ip.To4() != nil {
+		return rrstype.A, nil
+	}
+	return rrstype.AAAA, nil
+}
+
+// NormalizeAddress returns the canonical representation of address,
+// or address itself if it is not an IP address.
+func NormalizeAddress(address string) string {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return address
+	}
+	return ip.String()
+}
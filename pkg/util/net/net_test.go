@@ -0,0 +1,55 @@
+/*
+ * Copyright (C) 2016 wikiwi.io
+ *
+ * This software may be modified and distributed under the terms
+ * of the MIT license. See the LICENSE file for details.
+ */
+
+package net
+
+import (
+	"testing"
+
+	"k8s.io/kubernetes/federation/pkg/dnsprovider/rrstype"
+)
+
+func TestRecordType(t *testing.T) {
+	testScenarios := []struct {
+		input  string
+		output rrstype.RrsType
+		err    bool
+	}{
+		{input: "1.2.3.4", output: rrstype.A},
+		{input: "2001:db8::1", output: rrstype.AAAA},
+		{input: "::ffff:1.2.3.4", output: rrstype.A},
+		{input: "invalid", err: true},
+		{input: "", err: true},
+	}
+	for _, x := range testScenarios {
+		output, err := RecordType(x.input)
+		if x.err != (err != nil) {
+			t.Errorf("unexpected error value for %q: %v", x.input, err)
+		}
+		if output != x.output {
+			t.Errorf("expect %v, but was %v", x.output, output)
+		}
+	}
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	testScenarios := []struct {
+		input  string
+		output string
+	}{
+		{input: "1.2.3.4", output: "1.2.3.4"},
+		{input: "2001:0db8:0000::0001", output: "2001:db8::1"},
+		{input: "::ffff:1.2.3.4", output: "1.2.3.4"},
+		{input: "invalid", output: "invalid"},
+	}
+	for _, x := range testScenarios {
+		output := NormalizeAddress(x.input)
+		if output != x.output {
+			t.Errorf("expect %v, but was %v", x.output, output)
+		}
+	}
+}
@@ -9,9 +9,12 @@ package integration
 
 import (
 	"fmt"
+	"strconv"
 	"sync"
 
 	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/errors"
+	"k8s.io/kubernetes/pkg/apis/extensions"
 	"k8s.io/kubernetes/pkg/client/unversioned/testclient"
 	"k8s.io/kubernetes/pkg/labels"
 	"k8s.io/kubernetes/pkg/runtime"
@@ -24,24 +27,44 @@ func newKubeFake(nodes ...api.Node) *kubeFake {
 	return f
 }
 
-// kubeFake implements a fake Kubernetes Client. It only deals with the Nodes Service and the verbs 'list', and 'watch'.
+// kubeFake implements a fake Kubernetes Client. It only deals with Nodes, Services and Ingresses and the verbs 'list', and 'watch',
+// with Endpoints and the verbs 'get', 'create' and 'update', and records created Events.
 // This implementation is Thread-Safe.
 type kubeFake struct {
 	*testclient.Fake
 	nodeList          api.NodeList
+	serviceList       api.ServiceList
+	ingressList       extensions.IngressList
 	initialNodes      []api.Node
 	lock              sync.Mutex
 	fakeWatch         *watch.FakeWatcher
+	serviceWatch      *watch.FakeWatcher
+	ingressWatch      *watch.FakeWatcher
 	watchRestrictions testclient.WatchRestrictions
+	endpoints         map[string]api.Endpoints
+	events            []api.Event
+	// serviceListGate blocks listing Services while it is held.
+	serviceListGate sync.Mutex
 }
 
 func (f *kubeFake) init(nodes []api.Node) {
 	f.fakeWatch = watch.NewFake()
+	f.serviceWatch = watch.NewFake()
+	f.ingressWatch = watch.NewFake()
 	fakeClient := &testclient.Fake{}
 	fakeClient.AddReactor("list", "nodes", f.reactor)
 	fakeClient.AddWatchReactor("nodes", f.reactorWatch)
+	fakeClient.AddReactor("list", "services", f.reactorServices)
+	fakeClient.AddWatchReactor("services", f.reactorWatchServices)
+	fakeClient.AddReactor("list", "ingresses", f.reactorIngresses)
+	fakeClient.AddWatchReactor("ingresses", f.reactorWatchIngresses)
+	fakeClient.AddReactor("get", "endpoints", f.reactorGetEndpoints)
+	fakeClient.AddReactor("create", "endpoints", f.reactorCreateEndpoints)
+	fakeClient.AddReactor("update", "endpoints", f.reactorUpdateEndpoints)
+	fakeClient.AddReactor("create", "events", f.reactorCreateEvents)
 	f.Fake = fakeClient
 	f.initialNodes = nodes
+	f.endpoints = map[string]api.Endpoints{}
 }
 
 func (f *kubeFake) reactor(action testclient.Action) (handled bool, ret runtime.Object, err error) {
@@ -122,3 +145,137 @@ func (f *kubeFake) ModifyNode(node api.Node) error {
 	}
 	return fmt.Errorf("Node %q not found", node.ObjectMeta.Name)
 }
+
+func (f *kubeFake) reactorServices(action testclient.Action) (handled bool, ret runtime.Object, err error) {
+	f.serviceListGate.Lock()
+	f.serviceListGate.Unlock()
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	serviceList := f.serviceList
+	return true, &serviceList, nil
+}
+
+// HoldServiceList blocks listing Services until ReleaseServiceList is called.
+func (f *kubeFake) HoldServiceList() {
+	f.serviceListGate.Lock()
+}
+
+// ReleaseServiceList unblocks listing Services.
+func (f *kubeFake) ReleaseServiceList() {
+	f.serviceListGate.Unlock()
+}
+
+func (f *kubeFake) reactorWatchServices(action testclient.Action) (bool, watch.Interface, error) {
+	return true, f.serviceWatch, nil
+}
+
+func (f *kubeFake) AddService(service api.Service) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.serviceList.Items = append(f.serviceList.Items, service)
+	go func(service *api.Service) {
+		f.serviceWatch.Add(service)
+	}(&service)
+}
+
+func (f *kubeFake) ModifyService(service api.Service) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for i, x := range f.serviceList.Items {
+		if x.Namespace == service.Namespace && x.Name == service.Name {
+			f.serviceList.Items = append(append(f.serviceList.Items[:i], service), f.serviceList.Items[i+1:]...)
+			go func(service *api.Service) {
+				f.serviceWatch.Modify(service)
+			}(&service)
+			return nil
+		}
+	}
+	return fmt.Errorf("Service %s/%s not found", service.Namespace, service.Name)
+}
+
+func (f *kubeFake) reactorIngresses(action testclient.Action) (handled bool, ret runtime.Object, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	ingressList := f.ingressList
+	return true, &ingressList, nil
+}
+
+func (f *kubeFake) reactorWatchIngresses(action testclient.Action) (bool, watch.Interface, error) {
+	return true, f.ingressWatch, nil
+}
+
+func (f *kubeFake) AddIngress(ingress extensions.Ingress) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.ingressList.Items = append(f.ingressList.Items, ingress)
+	go func(ingress *extensions.Ingress) {
+		f.ingressWatch.Add(ingress)
+	}(&ingress)
+}
+
+// copyEndpoints returns a copy of endpoints that does not share its annotations.
+func copyEndpoints(endpoints api.Endpoints) *api.Endpoints {
+	annotations := map[string]string{}
+	for k, v := range endpoints.Annotations {
+		annotations[k] = v
+	}
+	endpoints.Annotations = annotations
+	return &endpoints
+}
+
+func (f *kubeFake) reactorGetEndpoints(action testclient.Action) (handled bool, ret runtime.Object, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	getAction := action.(testclient.GetAction)
+	endpoints, ok := f.endpoints[getAction.GetNamespace()+"/"+getAction.GetName()]
+	if !ok {
+		return true, nil, errors.NewNotFound(api.Resource("endpoints"), getAction.GetName())
+	}
+	return true, copyEndpoints(endpoints), nil
+}
+
+func (f *kubeFake) reactorCreateEndpoints(action testclient.Action) (handled bool, ret runtime.Object, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	endpoints := *action.(testclient.CreateAction).GetObject().(*api.Endpoints)
+	key := endpoints.Namespace + "/" + endpoints.Name
+	if _, ok := f.endpoints[key]; ok {
+		return true, nil, errors.NewAlreadyExists(api.Resource("endpoints"), endpoints.Name)
+	}
+	endpoints.ResourceVersion = "1"
+	f.endpoints[key] = *copyEndpoints(endpoints)
+	return true, copyEndpoints(endpoints), nil
+}
+
+func (f *kubeFake) reactorUpdateEndpoints(action testclient.Action) (handled bool, ret runtime.Object, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	endpoints := *action.(testclient.UpdateAction).GetObject().(*api.Endpoints)
+	key := endpoints.Namespace + "/" + endpoints.Name
+	old, ok := f.endpoints[key]
+	if !ok {
+		return true, nil, errors.NewNotFound(api.Resource("endpoints"), endpoints.Name)
+	}
+	if old.ResourceVersion != endpoints.ResourceVersion {
+		return true, nil, errors.NewConflict(api.Resource("endpoints"), endpoints.Name, fmt.Errorf("resource version mismatch"))
+	}
+	version, _ := strconv.Atoi(old.ResourceVersion)
+	endpoints.ResourceVersion = strconv.Itoa(version + 1)
+	f.endpoints[key] = *copyEndpoints(endpoints)
+	return true, copyEndpoints(endpoints), nil
+}
+
+func (f *kubeFake) reactorCreateEvents(action testclient.Action) (handled bool, ret runtime.Object, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	event := *action.(testclient.CreateAction).GetObject().(*api.Event)
+	f.events = append(f.events, event)
+	return true, &event, nil
+}
+
+// RecordedEvents returns the Events created so far.
+func (f *kubeFake) RecordedEvents() []api.Event {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]api.Event{}, f.events...)
+}
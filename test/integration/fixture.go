@@ -9,6 +9,7 @@ package integration
 
 import (
 	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/apis/extensions"
 )
 
 // k8sFixture is added as the initial Kubernetes resources for the integration tests.
@@ -65,3 +66,103 @@ var k8sFixture = []api.Node{
 		},
 	},
 }
+
+// dualStackNode is a Ready Node with an IPv4 and an IPv6 external address.
+var dualStackNode = api.Node{
+	ObjectMeta: api.ObjectMeta{Name: "node5"},
+	Status: api.NodeStatus{
+		Addresses: []api.NodeAddress{
+			api.NodeAddress{Type: api.NodeExternalIP, Address: "5.5.5.5"},
+			api.NodeAddress{Type: api.NodeExternalIP, Address: "2001:db8::5"},
+		},
+		Conditions: []api.NodeCondition{api.NodeCondition{
+			Type:   api.NodeReady,
+			Status: api.ConditionTrue,
+		}},
+	},
+}
+
+// loadBalancerService is a published LoadBalancer Service.
+var loadBalancerService = api.Service{
+	ObjectMeta: api.ObjectMeta{
+		Name:        "web",
+		Namespace:   "default",
+		Annotations: map[string]string{"kube-dns-sync/publish": "true"},
+	},
+	Spec: api.ServiceSpec{Type: api.ServiceTypeLoadBalancer},
+	Status: api.ServiceStatus{
+		LoadBalancer: api.LoadBalancerStatus{
+			Ingress: []api.LoadBalancerIngress{{IP: "8.8.8.8"}, {Hostname: "lb.example.com"}},
+		},
+	},
+}
+
+// nodePortService is a published NodePort Service.
+var nodePortService = api.Service{
+	ObjectMeta: api.ObjectMeta{
+		Name:        "api",
+		Namespace:   "kube-system",
+		Annotations: map[string]string{"kube-dns-sync/publish": "true"},
+	},
+	Spec: api.ServiceSpec{Type: api.ServiceTypeNodePort},
+}
+
+// unpublishedService is a LoadBalancer Service that did not opt in.
+var unpublishedService = api.Service{
+	ObjectMeta: api.ObjectMeta{Name: "private", Namespace: "default"},
+	Spec:       api.ServiceSpec{Type: api.ServiceTypeLoadBalancer},
+	Status: api.ServiceStatus{
+		LoadBalancer: api.LoadBalancerStatus{
+			Ingress: []api.LoadBalancerIngress{{IP: "9.9.9.9"}},
+		},
+	},
+}
+
+// loadBalancerIngress is an Ingress with a load balancer IP and a host outside of the zone.
+var loadBalancerIngress = extensions.Ingress{
+	ObjectMeta: api.ObjectMeta{Name: "shop", Namespace: "default"},
+	Spec: extensions.IngressSpec{
+		Rules: []extensions.IngressRule{{Host: "shop.test.com"}, {Host: "shop.other.com"}},
+	},
+	Status: extensions.IngressStatus{
+		LoadBalancer: api.LoadBalancerStatus{
+			Ingress: []api.LoadBalancerIngress{{IP: "8.8.4.4"}},
+		},
+	},
+}
+
+// nodeIngress is an Ingress without load balancer.
+var nodeIngress = extensions.Ingress{
+	ObjectMeta: api.ObjectMeta{Name: "blog", Namespace: "default"},
+	Spec: extensions.IngressSpec{
+		Rules: []extensions.IngressRule{{Host: "blog.test.com"}, {Host: "www.blog.test.com"}},
+	},
+}
+
+// hostnameService is a published LoadBalancer Service whose load balancer only has a hostname.
+var hostnameService = api.Service{
+	ObjectMeta: api.ObjectMeta{
+		Name:        "elb",
+		Namespace:   "default",
+		Annotations: map[string]string{"kube-dns-sync/publish": "true"},
+	},
+	Spec: api.ServiceSpec{Type: api.ServiceTypeLoadBalancer},
+	Status: api.ServiceStatus{
+		LoadBalancer: api.LoadBalancerStatus{
+			Ingress: []api.LoadBalancerIngress{{Hostname: "elb.example.com"}},
+		},
+	},
+}
+
+// hostnameIngress is an Ingress whose load balancer only has a hostname.
+var hostnameIngress = extensions.Ingress{
+	ObjectMeta: api.ObjectMeta{Name: "elb", Namespace: "default"},
+	Spec: extensions.IngressSpec{
+		Rules: []extensions.IngressRule{{Host: "elb.test.com"}},
+	},
+	Status: extensions.IngressStatus{
+		LoadBalancer: api.LoadBalancerStatus{
+			Ingress: []api.LoadBalancerIngress{{Hostname: "elb.example.com"}},
+		},
+	},
+}
@@ -8,10 +8,18 @@
 package integration
 
 import (
+	"fmt"
+	"sync"
+
 	"github.com/onsi/gomega"
 
-	"github.com/wikiwi/kube-dns-sync/pkg/controller"
+	"k8s.io/kubernetes/federation/pkg/dnsprovider"
+	"k8s.io/kubernetes/federation/pkg/dnsprovider/rrstype"
+	"k8s.io/kubernetes/pkg/api"
 	"k8s.io/kubernetes/pkg/labels"
+
+	"github.com/wikiwi/kube-dns-sync/pkg/controller"
+	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/dnsproviderfake"
 )
 
 // runAndReportExit runs given Controller, expects err=nil, and notifies channel report.
@@ -34,3 +42,107 @@ func parseSelectorOrDie(s string) labels.Selector {
 	}
 	return sel
 }
+
+// ownershipRecord returns the TXT Record marking name as owned by the default owner.
+func ownershipRecord(name string, ttl int64) dnsprovider.ResourceRecordSet {
+	return &dnsproviderfake.ResourceRecordSetFake{
+		RRSName:  "_kube-dns-sync." + name,
+		RRSTTL:   ttl,
+		RRSDatas: []string{`"heritage=kube-dns-sync,owner=default"`},
+		RRSType:  rrstype.RrsType("TXT"),
+	}
+}
+
+// staticSource is a controller.Source providing a fixed list of Endpoints.
+type staticSource []controller.Endpoint
+
+// Endpoints implements controller.Source.
+func (s staticSource) Endpoints() ([]controller.Endpoint, error) {
+	return s, nil
+}
+
+// Changes implements controller.Source, the Endpoints never change.
+func (s staticSource) Changes() <-chan struct{} {
+	return nil
+}
+
+// countingSource is a controller.Source without Endpoints counting the syncs.
+type countingSource struct {
+	staticSource
+	lock  sync.Mutex
+	count int
+}
+
+// Endpoints implements controller.Source.
+func (s *countingSource) Endpoints() ([]controller.Endpoint, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.count++
+	return nil, nil
+}
+
+// Count returns the number of syncs.
+func (s *countingSource) Count() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.count
+}
+
+// blockingSource is a countingSource whose Endpoints block while hold is locked,
+// which keeps a sync running.
+type blockingSource struct {
+	countingSource
+	hold sync.Mutex
+}
+
+// Endpoints implements controller.Source.
+func (s *blockingSource) Endpoints() ([]controller.Endpoint, error) {
+	s.hold.Lock()
+	defer s.hold.Unlock()
+	return s.countingSource.Endpoints()
+}
+
+// unbatchedZone hides the changeset.Batcher implementation of the fake
+// ResourceRecordSets to exercise the fallback for providers without change sets.
+type unbatchedZone struct {
+	*dnsproviderfake.ZoneFake
+}
+
+// ResourceRecordSets returns the ResourceRecordSets without Apply.
+func (z unbatchedZone) ResourceRecordSets() (dnsprovider.ResourceRecordSets, bool) {
+	return struct{ dnsprovider.ResourceRecordSets }{z.RRS}, true
+}
+
+// failAddressAdd returns an AddHook failing the Add of Records containing address.
+func failAddressAdd(address string) func(dnsprovider.ResourceRecordSet) error {
+	return func(rrs dnsprovider.ResourceRecordSet) error {
+		for _, x := range rrs.Rrdatas() {
+			if x == address {
+				return fmt.Errorf("injected failure for %q", address)
+			}
+		}
+		return nil
+	}
+}
+
+// eventMessages returns the messages of the Events with reason involving the object of kind and name.
+func eventMessages(events []api.Event, kind, name, reason string) []string {
+	var messages []string
+	for _, x := range events {
+		if x.InvolvedObject.Kind == kind && x.InvolvedObject.Name == name && x.Reason == reason {
+			messages = append(messages, x.Message)
+		}
+	}
+	return messages
+}
+
+// failTimes returns an AddHook failing the first n Adds.
+func failTimes(n int) func(dnsprovider.ResourceRecordSet) error {
+	return func(rrs dnsprovider.ResourceRecordSet) error {
+		if n > 0 {
+			n--
+			return fmt.Errorf("injected failure")
+		}
+		return nil
+	}
+}
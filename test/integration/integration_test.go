@@ -8,20 +8,26 @@
 package integration
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	"github.com/kr/pretty"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"k8s.io/kubernetes/federation/pkg/dnsprovider"
 	"k8s.io/kubernetes/federation/pkg/dnsprovider/rrstype"
 	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
 
 	"github.com/wikiwi/kube-dns-sync/pkg/controller"
 	k8sutil "github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes"
 	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/dnsproviderfake"
+	"github.com/wikiwi/kube-dns-sync/pkg/util/kubernetes/routing"
 )
 
 type Test struct {
@@ -73,6 +79,7 @@ var _ = Describe("Controller", func() {
 		Test{
 			Expected: []dnsprovider.ResourceRecordSet{
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
 			},
 			ControllerOptions: controller.Options{
 				DNSProvider:  dns,
@@ -88,6 +95,7 @@ var _ = Describe("Controller", func() {
 		Test{
 			Expected: []dnsprovider.ResourceRecordSet{
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "internalip.test.com.", RRSTTL: 60, RRSDatas: []string{"127.0.0.1", "127.0.0.4"}, RRSType: rrstype.A},
+				ownershipRecord("internalip.test.com.", 60),
 			},
 			ControllerOptions: controller.Options{
 				DNSProvider:  dns,
@@ -103,6 +111,7 @@ var _ = Describe("Controller", func() {
 		Test{
 			Expected: []dnsprovider.ResourceRecordSet{
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "legacyhostip.test.com.", RRSTTL: 60, RRSDatas: []string{"2.2.2.2"}, RRSType: rrstype.A},
+				ownershipRecord("legacyhostip.test.com.", 60),
 			},
 			ControllerOptions: controller.Options{
 				DNSProvider:  dns,
@@ -118,8 +127,11 @@ var _ = Describe("Controller", func() {
 		Test{
 			Expected: []dnsprovider.ResourceRecordSet{
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "internalip.test.com.", RRSTTL: 60, RRSDatas: []string{"127.0.0.1", "127.0.0.4"}, RRSType: rrstype.A},
+				ownershipRecord("internalip.test.com.", 60),
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "legacyhostip.test.com.", RRSTTL: 60, RRSDatas: []string{"2.2.2.2"}, RRSType: rrstype.A},
+				ownershipRecord("legacyhostip.test.com.", 60),
 			},
 			ControllerOptions: controller.Options{
 				DNSProvider:  dns,
@@ -135,6 +147,7 @@ var _ = Describe("Controller", func() {
 		Test{
 			Expected: []dnsprovider.ResourceRecordSet{
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "legacyhostip.test.com.", RRSTTL: 200, RRSDatas: []string{"2.2.2.2"}, RRSType: rrstype.A},
+				ownershipRecord("legacyhostip.test.com.", 200),
 			},
 			ControllerOptions: controller.Options{
 				DNSProvider:  dns,
@@ -150,6 +163,7 @@ var _ = Describe("Controller", func() {
 		Test{
 			Expected: []dnsprovider.ResourceRecordSet{
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
 			},
 			ControllerOptions: controller.Options{
 				DNSProvider:  dns,
@@ -171,6 +185,7 @@ var _ = Describe("Controller", func() {
 		Test{
 			Expected: []dnsprovider.ResourceRecordSet{
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
 			},
 			ControllerOptions: controller.Options{
 				DNSProvider:  dns,
@@ -203,6 +218,7 @@ var _ = Describe("Controller", func() {
 		Test{
 			Expected: []dnsprovider.ResourceRecordSet{
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
 			},
 			ControllerOptions: controller.Options{
 				DNSProvider:  dns,
@@ -223,6 +239,7 @@ var _ = Describe("Controller", func() {
 		Test{
 			Expected: []dnsprovider.ResourceRecordSet{
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4", "5.5.5.5"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
 			},
 			ControllerOptions: controller.Options{
 				DNSProvider:  dns,
@@ -254,6 +271,7 @@ var _ = Describe("Controller", func() {
 		Test{
 			Expected: []dnsprovider.ResourceRecordSet{
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"6.6.6.6", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
 			},
 			ControllerOptions: controller.Options{
 				DNSProvider:  dns,
@@ -286,6 +304,7 @@ var _ = Describe("Controller", func() {
 		Test{
 			Expected: []dnsprovider.ResourceRecordSet{
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("test.com.", 60),
 			},
 			ControllerOptions: controller.Options{
 				DNSProvider:     dns,
@@ -302,7 +321,9 @@ var _ = Describe("Controller", func() {
 		Test{
 			Expected: []dnsprovider.ResourceRecordSet{
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "internalip.test.com.", RRSTTL: 60, RRSDatas: []string{"127.0.0.1", "127.0.0.4"}, RRSType: rrstype.A},
+				ownershipRecord("internalip.test.com.", 60),
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("test.com.", 60),
 			},
 			ControllerOptions: controller.Options{
 				DNSProvider:     dns,
@@ -320,7 +341,9 @@ var _ = Describe("Controller", func() {
 		Test{
 			Expected: []dnsprovider.ResourceRecordSet{
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("test.com.", 60),
 			},
 			ControllerOptions: controller.Options{
 				DNSProvider:     dns,
@@ -338,6 +361,7 @@ var _ = Describe("Controller", func() {
 		Test{
 			Expected: []dnsprovider.ResourceRecordSet{
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "keepitCNAME.test.com.", RRSType: rrstype.CNAME},
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "keepitA.test.com.", RRSType: rrstype.A},
 			},
@@ -361,8 +385,11 @@ var _ = Describe("Controller", func() {
 		Test{
 			Expected: []dnsprovider.ResourceRecordSet{
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"6.6.6.6", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "internalip.test.com.", RRSTTL: 60, RRSDatas: []string{"127.0.0.4", "127.0.0.6"}, RRSType: rrstype.A},
+				ownershipRecord("internalip.test.com.", 60),
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "test.com.", RRSTTL: 60, RRSDatas: []string{"6.6.6.6", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("test.com.", 60),
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "keepit.test.com.", RRSType: rrstype.A},
 			},
 			ControllerOptions: controller.Options{
@@ -378,7 +405,7 @@ var _ = Describe("Controller", func() {
 				client.DeleteNode("node1")
 				rrs.Add(&dnsproviderfake.ResourceRecordSetFake{RRSName: "keepit.test.com.", RRSType: rrstype.A})
 				time.Sleep(500 * time.Millisecond)
-				rrs.Remove(&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com."})
+				rrs.Remove(&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSType: rrstype.A})
 				time.Sleep(500 * time.Millisecond)
 				client.AddNode(api.Node{
 					ObjectMeta: api.ObjectMeta{Name: "node6"},
@@ -403,6 +430,7 @@ var _ = Describe("Controller", func() {
 		Test{
 			Expected: []dnsprovider.ResourceRecordSet{
 				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
 			},
 			ControllerOptions: controller.Options{
 				DNSProvider:  dns,
@@ -415,4 +443,1577 @@ var _ = Describe("Controller", func() {
 			},
 		}.Run(rrs)
 	})
+
+	It("should remove Record when no Ready Node is left", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				TTL:          60,
+				AddressTypes: []api.NodeAddressType{api.NodeLegacyHostIP},
+				SyncInterval: 500 * time.Millisecond,
+			},
+			Modify: func(c *controller.Controller) {
+				client.ModifyNode(api.Node{
+					ObjectMeta: api.ObjectMeta{Name: "node2"},
+					Status: api.NodeStatus{
+						Addresses: []api.NodeAddress{
+							api.NodeAddress{Type: api.NodeLegacyHostIP, Address: "2.2.2.2"},
+						},
+						Conditions: []api.NodeCondition{api.NodeCondition{
+							Type:   api.NodeReady,
+							Status: api.ConditionFalse,
+						}},
+					},
+				})
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should keep stale Record when configured", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "legacyhostip.test.com.", RRSTTL: 60, RRSDatas: []string{"2.2.2.2"}, RRSType: rrstype.A},
+				ownershipRecord("legacyhostip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:      dns,
+				ZoneName:         "test.com.",
+				Client:           client,
+				TTL:              60,
+				AddressTypes:     []api.NodeAddressType{api.NodeLegacyHostIP},
+				SyncInterval:     500 * time.Millisecond,
+				KeepStaleRecords: true,
+			},
+			Modify: func(c *controller.Controller) {
+				client.DeleteNode("node2")
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should remove Records of address types that are not synced anymore", func() {
+		rrs.Add(&dnsproviderfake.ResourceRecordSetFake{RRSName: "internalip.test.com.", RRSTTL: 60, RRSDatas: []string{"127.0.0.1"}, RRSType: rrstype.A})
+		rrs.Add(ownershipRecord("internalip.test.com.", 60))
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				TTL:          60,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+			},
+		}.Run(rrs)
+	})
+
+	It("should not touch Records without ownership", func() {
+		rrs.Add(&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"9.9.9.9"}, RRSType: rrstype.A})
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"9.9.9.9"}, RRSType: rrstype.A},
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				TTL:          60,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+			},
+		}.Run(rrs)
+	})
+
+	It("should adopt Records without ownership when configured", func() {
+		rrs.Add(&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"9.9.9.9"}, RRSType: rrstype.A})
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:   dns,
+				ZoneName:      "test.com.",
+				Client:        client,
+				TTL:           60,
+				AddressTypes:  []api.NodeAddressType{api.NodeExternalIP},
+				AdoptExisting: true,
+			},
+		}.Run(rrs)
+	})
+
+	It("should not adopt Records of other owners", func() {
+		other := &dnsproviderfake.ResourceRecordSetFake{RRSName: "_kube-dns-sync.externalip.test.com.", RRSTTL: 60, RRSDatas: []string{`"heritage=kube-dns-sync,owner=other"`}, RRSType: rrstype.RrsType("TXT")}
+		rrs.Add(other)
+		rrs.Add(&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"9.9.9.9"}, RRSType: rrstype.A})
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"9.9.9.9"}, RRSType: rrstype.A},
+				other,
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:   dns,
+				ZoneName:      "test.com.",
+				Client:        client,
+				TTL:           60,
+				AddressTypes:  []api.NodeAddressType{api.NodeExternalIP},
+				AdoptExisting: true,
+			},
+		}.Run(rrs)
+	})
+
+	It("should sync the apex zone next to foreign TXT Records", func() {
+		spf := &dnsproviderfake.ResourceRecordSetFake{RRSName: "test.com.", RRSTTL: 300, RRSDatas: []string{`"v=spf1 include:_spf.example.com ~all"`}, RRSType: rrstype.RrsType("TXT")}
+		rrs.Add(spf)
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("test.com.", 60),
+				spf,
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:     dns,
+				ZoneName:        "test.com.",
+				Client:          client,
+				TTL:             60,
+				ApexAddressType: api.NodeExternalIP,
+			},
+		}.Run(rrs)
+	})
+
+	It("should keep foreign TXT Records when removing the apex Records", func() {
+		spf := &dnsproviderfake.ResourceRecordSetFake{RRSName: "test.com.", RRSTTL: 300, RRSDatas: []string{`"v=spf1 include:_spf.example.com ~all"`}, RRSType: rrstype.RrsType("TXT")}
+		rrs.Add(spf)
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{spf},
+			ControllerOptions: controller.Options{
+				DNSProvider:     dns,
+				ZoneName:        "test.com.",
+				Client:          client,
+				TTL:             60,
+				ApexAddressType: api.NodeExternalIP,
+			},
+			Modify: func(c *controller.Controller) {
+				client.DeleteNode("node1")
+				client.DeleteNode("node4")
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should not touch Records of other owners", func() {
+		other := &dnsproviderfake.ResourceRecordSetFake{RRSName: "_kube-dns-sync.internalip.test.com.", RRSTTL: 60, RRSDatas: []string{`"heritage=kube-dns-sync,owner=other"`}, RRSType: rrstype.RrsType("TXT")}
+		rrs.Add(other)
+		rrs.Add(&dnsproviderfake.ResourceRecordSetFake{RRSName: "internalip.test.com.", RRSTTL: 60, RRSDatas: []string{"127.0.0.9"}, RRSType: rrstype.A})
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "internalip.test.com.", RRSTTL: 60, RRSDatas: []string{"127.0.0.9"}, RRSType: rrstype.A},
+				other,
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				TTL:          60,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP, api.NodeInternalIP},
+			},
+		}.Run(rrs)
+	})
+
+	It("should sync a Record per Node when configured", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "node1.externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1"}, RRSType: rrstype.A},
+				ownershipRecord("node1.externalip.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "node4.externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("node4.externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				TTL:          60,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				NodeRecords:  true,
+			},
+		}.Run(rrs)
+	})
+
+	It("should remove the Record of a Node when it gets deleted", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "test.com.", RRSTTL: 60, RRSDatas: []string{"4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "node4.test.com.", RRSTTL: 60, RRSDatas: []string{"4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("node4.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:     dns,
+				ZoneName:        "test.com.",
+				Client:          client,
+				TTL:             60,
+				ApexAddressType: api.NodeExternalIP,
+				SyncInterval:    500 * time.Millisecond,
+				NodeRecords:     true,
+			},
+			Modify: func(c *controller.Controller) {
+				client.DeleteNode("node1")
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should use record name template when configured", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "bar.nodes.test.com.", RRSTTL: 60, RRSDatas: []string{"4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("bar.nodes.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:        dns,
+				ZoneName:           "test.com.",
+				Client:             client,
+				TTL:                60,
+				AddressTypes:       []api.NodeAddressType{api.NodeExternalIP},
+				RecordNameTemplate: "{{.Labels.foo}}.nodes.{{.Zone}}",
+			},
+		}.Run(rrs)
+	})
+
+	It("should fail on invalid record name template", func() {
+		_, err := controller.New(&controller.Options{
+			DNSProvider:        dns,
+			ZoneName:           "test.com.",
+			Client:             client,
+			AddressTypes:       []api.NodeAddressType{api.NodeExternalIP},
+			RecordNameTemplate: "{{.AddressType",
+		})
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("should sync IPv6 addresses to AAAA Records", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4", "5.5.5.5"}, RRSType: rrstype.A},
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"2001:db8::5"}, RRSType: rrstype.AAAA},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				TTL:          60,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval: 500 * time.Millisecond,
+			},
+			Modify: func(c *controller.Controller) {
+				client.AddNode(dualStackNode)
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should only sync IPv6 addresses when configured", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"2001:db8::5"}, RRSType: rrstype.AAAA},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				TTL:          60,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval: 500 * time.Millisecond,
+				IPFamily:     controller.IPv6,
+			},
+			Modify: func(c *controller.Controller) {
+				client.AddNode(dualStackNode)
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should remove AAAA Record when IPv6 address is gone", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				TTL:          60,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval: 500 * time.Millisecond,
+			},
+			Modify: func(c *controller.Controller) {
+				client.AddNode(dualStackNode)
+				time.Sleep(500 * time.Millisecond)
+				client.DeleteNode(dualStackNode.Name)
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should sync published Services when configured", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "web.default.test.com.", RRSTTL: 60, RRSDatas: []string{"8.8.8.8"}, RRSType: rrstype.A},
+				ownershipRecord("web.default.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "api.kube-system.test.com.", RRSTTL: 60, RRSDatas: []string{"127.0.0.1", "127.0.0.4"}, RRSType: rrstype.A},
+				ownershipRecord("api.kube-system.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:        dns,
+				ZoneName:           "test.com.",
+				Client:             client,
+				TTL:                60,
+				AddressTypes:       []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval:       500 * time.Millisecond,
+				SyncServices:       true,
+				ServiceAddressType: api.NodeInternalIP,
+			},
+			Modify: func(c *controller.Controller) {
+				client.AddService(loadBalancerService)
+				client.AddService(nodePortService)
+				client.AddService(unpublishedService)
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should remove Service Record when Service is unpublished", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				TTL:          60,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval: 500 * time.Millisecond,
+				SyncServices: true,
+			},
+			Modify: func(c *controller.Controller) {
+				client.AddService(loadBalancerService)
+				time.Sleep(500 * time.Millisecond)
+				unpublished := loadBalancerService
+				unpublished.Annotations = nil
+				client.ModifyService(unpublished)
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should sync Ingress hosts when configured", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "shop.test.com.", RRSTTL: 60, RRSDatas: []string{"8.8.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("shop.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "blog.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("blog.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "www.blog.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("www.blog.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:   dns,
+				ZoneName:      "test.com.",
+				Client:        client,
+				TTL:           60,
+				AddressTypes:  []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval:  500 * time.Millisecond,
+				SyncIngresses: true,
+			},
+			Modify: func(c *controller.Controller) {
+				client.AddIngress(loadBalancerIngress)
+				client.AddIngress(nodeIngress)
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should sync Endpoints of additional Sources", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4", "7.7.7.7"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "static.test.com.", RRSTTL: 60, RRSDatas: []string{"10.0.0.1"}, RRSType: rrstype.A},
+				ownershipRecord("static.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				TTL:          60,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				Sources: []controller.Source{staticSource{
+					{DNSName: "static.test.com.", Targets: []string{"10.0.0.1"}},
+					{DNSName: "externalip.test.com.", Targets: []string{"7.7.7.7"}},
+					{DNSName: "outside.other.com.", Targets: []string{"10.0.0.2"}},
+				}},
+			},
+		}.Run(rrs)
+	})
+
+	It("should sync multiple zones with their own settings", func() {
+		zones, _ := dns.Zones()
+		zone, err := zones.New("internal.test.com.")
+		Expect(err).To(BeNil())
+		_, err = zones.Add(zone)
+		Expect(err).To(BeNil())
+		internalRRS, _ := zone.ResourceRecordSets()
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			Modify: func(c *controller.Controller) {
+				ls, err := internalRRS.List()
+				Expect(err).To(BeNil())
+				expected := []dnsprovider.ResourceRecordSet{
+					&dnsproviderfake.ResourceRecordSetFake{RRSName: "internalip.internal.test.com.", RRSTTL: 300, RRSDatas: []string{"127.0.0.4"}, RRSType: rrstype.A},
+					ownershipRecord("internalip.internal.test.com.", 300),
+				}
+				if !k8sutil.EqualRRSList(ls, expected) {
+					pretty.Fprintf(GinkgoWriter, "# Received Value:\n%# v\n", ls)
+					Fail("Unexpected DNS Records in zone internal.test.com.")
+				}
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider: dns,
+				Client:      client,
+				Zones: []controller.ZoneOptions{
+					{Name: "test.com.", AddressTypes: []api.NodeAddressType{api.NodeExternalIP}},
+					{Name: "internal.test.com.", TTL: 300, AddressTypes: []api.NodeAddressType{api.NodeInternalIP}, Selector: parseSelectorOrDie("foo=bar")},
+				},
+				SyncInterval: 500 * time.Millisecond,
+			},
+		}.Run(rrs)
+	})
+
+	It("should create a missing zone", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{},
+			Modify: func(c *controller.Controller) {
+				zones, _ := dns.Zones()
+				zoneList, err := zones.List()
+				Expect(err).To(BeNil())
+				var created dnsprovider.Zone
+				for _, x := range zoneList {
+					if x.Name() == "new.com." {
+						created = x
+					}
+				}
+				Expect(created).NotTo(BeNil())
+				createdRRS, _ := created.ResourceRecordSets()
+				ls, err := createdRRS.List()
+				Expect(err).To(BeNil())
+				expected := []dnsprovider.ResourceRecordSet{
+					&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.new.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+					ownershipRecord("externalip.new.com.", 60),
+				}
+				if !k8sutil.EqualRRSList(ls, expected) {
+					pretty.Fprintf(GinkgoWriter, "# Received Value:\n%# v\n", ls)
+					Fail("Unexpected DNS Records in zone new.com.")
+				}
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "new.com.",
+				Client:       client,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				CreateZone:   true,
+				SyncInterval: 500 * time.Millisecond,
+			},
+		}.Run(rrs)
+	})
+
+	It("should not modify the zone in dry run mode", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				DryRun:       true,
+				SyncInterval: 500 * time.Millisecond,
+			},
+		}.Run(rrs)
+	})
+
+	It("should plan adds, updates and removes", func() {
+		_, err := rrs.Add(rrs.New("externalip.test.com.", []string{"9.9.9.9"}, 60, rrstype.A))
+		Expect(err).To(BeNil())
+		_, err = rrs.Add(ownershipRecord("externalip.test.com.", 60))
+		Expect(err).To(BeNil())
+		_, err = rrs.Add(rrs.New("legacyhostip.test.com.", []string{"2.2.2.2"}, 60, rrstype.A))
+		Expect(err).To(BeNil())
+		_, err = rrs.Add(ownershipRecord("legacyhostip.test.com.", 60))
+		Expect(err).To(BeNil())
+
+		c, err := controller.New(&controller.Options{
+			DNSProvider:  dns,
+			ZoneName:     "test.com.",
+			Client:       client,
+			AddressTypes: []api.NodeAddressType{api.NodeExternalIP, api.NodeInternalIP},
+		})
+		Expect(err).To(BeNil())
+		changes, err := c.Plan()
+		Expect(err).To(BeNil())
+
+		type summary struct {
+			Action  controller.Action
+			Type    string
+			Name    string
+			Rrdatas []string
+		}
+		var summaries []summary
+		for _, x := range changes {
+			summaries = append(summaries, summary{x.Action, x.Type, x.Name, x.Rrdatas})
+		}
+		Expect(summaries).To(Equal([]summary{
+			{controller.ActionUpdate, "A", "externalip.test.com.", []string{"1.1.1.1", "4.4.4.4"}},
+			{controller.ActionAdd, "TXT", "_kube-dns-sync.internalip.test.com.", []string{`"heritage=kube-dns-sync,owner=default"`}},
+			{controller.ActionAdd, "A", "internalip.test.com.", []string{"127.0.0.1", "127.0.0.4"}},
+			{controller.ActionRemove, "A", "legacyhostip.test.com.", []string{"2.2.2.2"}},
+			{controller.ActionRemove, "TXT", "_kube-dns-sync.legacyhostip.test.com.", []string{`"heritage=kube-dns-sync,owner=default"`}},
+		}))
+
+		ls, err := rrs.List()
+		Expect(err).To(BeNil())
+		Expect(ls).To(HaveLen(4))
+	})
+
+	Describe("replacing diverged Records", func() {
+		var fakeRRS *dnsproviderfake.ResourceRecordSetsFake
+		divergedRecords := []dnsprovider.ResourceRecordSet{
+			&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"9.9.9.9"}, RRSType: rrstype.A},
+			ownershipRecord("externalip.test.com.", 60),
+		}
+		options := controller.Options{
+			ZoneName:     "test.com.",
+			AddressTypes: []api.NodeAddressType{api.NodeExternalIP, api.NodeInternalIP},
+			SyncInterval: 500 * time.Millisecond,
+		}
+
+		BeforeEach(func() {
+			fakeRRS = rrs.(*dnsproviderfake.ResourceRecordSetsFake)
+			for _, x := range divergedRecords {
+				_, err := rrs.Add(x)
+				Expect(err).To(BeNil())
+			}
+			options.DNSProvider = dns
+			options.Client = client
+		})
+
+		It("should apply all changes atomically", func() {
+			fakeRRS.AddHook = failAddressAdd("1.1.1.1")
+			Test{
+				Expected:          divergedRecords,
+				ControllerOptions: options,
+			}.Run(rrs)
+		})
+
+		It("should replace Records without change sets", func() {
+			dns.ZonesFake.ZoneList[0] = unbatchedZone{dns.ZonesFake.ZoneList[0].(*dnsproviderfake.ZoneFake)}
+			Test{
+				Expected: []dnsprovider.ResourceRecordSet{
+					&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+					ownershipRecord("externalip.test.com.", 60),
+					&dnsproviderfake.ResourceRecordSetFake{RRSName: "internalip.test.com.", RRSTTL: 60, RRSDatas: []string{"127.0.0.1", "127.0.0.4"}, RRSType: rrstype.A},
+					ownershipRecord("internalip.test.com.", 60),
+				},
+				ControllerOptions: options,
+			}.Run(rrs)
+		})
+
+		It("should restore replaced Records when adding fails without change sets", func() {
+			dns.ZonesFake.ZoneList[0] = unbatchedZone{dns.ZonesFake.ZoneList[0].(*dnsproviderfake.ZoneFake)}
+			fakeRRS.AddHook = failAddressAdd("1.1.1.1")
+			Test{
+				Expected: append([]dnsprovider.ResourceRecordSet{
+					&dnsproviderfake.ResourceRecordSetFake{RRSName: "internalip.test.com.", RRSTTL: 60, RRSDatas: []string{"127.0.0.1", "127.0.0.4"}, RRSType: rrstype.A},
+					ownershipRecord("internalip.test.com.", 60),
+				}, divergedRecords...),
+				ControllerOptions: options,
+			}.Run(rrs)
+		})
+	})
+
+	It("should expose metrics", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			Modify: func(c *controller.Controller) {
+				recorder := httptest.NewRecorder()
+				req, err := http.NewRequest("GET", "/metrics", nil)
+				Expect(err).To(BeNil())
+				prometheus.Handler().ServeHTTP(recorder, req)
+				body := recorder.Body.String()
+				Expect(body).To(ContainSubstring("kube_dns_sync_sync_duration_seconds_count"))
+				Expect(body).To(ContainSubstring("kube_dns_sync_sync_successes_total"))
+				Expect(body).To(ContainSubstring("kube_dns_sync_last_successful_sync_timestamp_seconds"))
+				Expect(body).To(ContainSubstring(`kube_dns_sync_record_changes_total{action="add",zone="test.com."}`))
+				Expect(body).To(ContainSubstring(`kube_dns_sync_records_unchanged{zone="test.com."} 1`))
+				Expect(body).To(ContainSubstring(`kube_dns_sync_nodes{address_type="externalip",condition="ready",zone="test.com."} 2`))
+				Expect(body).To(ContainSubstring(`kube_dns_sync_nodes{address_type="externalip",condition="not_ready",zone="test.com."} 1`))
+				Expect(body).To(ContainSubstring(`kube_dns_sync_informer_events_total{event="add",resource="node"}`))
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval: 500 * time.Millisecond,
+			},
+		}.Run(rrs)
+	})
+
+	It("should report ready and healthy after a successful sync", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			Modify: func(c *controller.Controller) {
+				Expect(c.Ready()).To(BeNil())
+				Expect(c.Healthy()).To(BeNil())
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval: 500 * time.Millisecond,
+			},
+		}.Run(rrs)
+	})
+
+	It("should not report ready when the last sync failed", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{},
+			Modify: func(c *controller.Controller) {
+				Expect(c.Ready()).NotTo(BeNil())
+				Expect(c.Healthy()).To(BeNil())
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "missing.com.",
+				Client:       client,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval: 500 * time.Millisecond,
+			},
+		}.Run(rrs)
+	})
+
+	It("should only sync from the elected leader and fail over", func() {
+		newReplica := func(identity string) *controller.Controller {
+			c, err := controller.New(&controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval: 500 * time.Millisecond,
+				LeaderElection: &controller.LeaderElectionOptions{
+					Identity:      identity,
+					LeaseDuration: 2 * time.Second,
+					RenewDeadline: time.Second,
+					RetryPeriod:   100 * time.Millisecond,
+				},
+			})
+			Expect(err).To(BeNil())
+			return c
+		}
+		expected := []dnsprovider.ResourceRecordSet{
+			&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+			ownershipRecord("externalip.test.com.", 60),
+		}
+
+		first, second := newReplica("first"), newReplica("second")
+		firstReport, secondReport := make(chan struct{}), make(chan struct{})
+		go runAndReportExit(first, firstReport)
+		time.Sleep(500 * time.Millisecond)
+		go runAndReportExit(second, secondReport)
+		time.Sleep(1 * time.Second)
+
+		Expect(first.IsLeader()).To(BeTrue())
+		Expect(second.IsLeader()).To(BeFalse())
+		Expect(second.Ready()).To(BeNil())
+		Expect(second.Healthy()).To(BeNil())
+		ls, err := rrs.List()
+		Expect(err).To(BeNil())
+		Expect(k8sutil.EqualRRSList(ls, expected)).To(BeTrue())
+
+		first.Stop()
+		waitForReport(firstReport)
+		Expect(first.IsLeader()).To(BeFalse())
+		// The lease of the stopped leader expires after LeaseDuration.
+		time.Sleep(3 * time.Second)
+		Expect(second.IsLeader()).To(BeTrue())
+		ls, err = rrs.List()
+		Expect(err).To(BeNil())
+		Expect(k8sutil.EqualRRSList(ls, expected)).To(BeTrue())
+
+		second.Stop()
+		waitForReport(secondReport)
+	})
+
+	It("should emit events about record changes", func() {
+		pod := &api.ObjectReference{Kind: "Pod", Namespace: "kube-system", Name: "kube-dns-sync"}
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				TTL:          60,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				Events:       true,
+				EventObject:  pod,
+			},
+			Modify: func(c *controller.Controller) {
+				events := client.RecordedEvents()
+				// The ownership Record doesn't emit an Event of its own.
+				Expect(eventMessages(events, "Pod", "kube-dns-sync", controller.EventReasonRecordAdded)).To(HaveLen(1))
+				Expect(eventMessages(events, "Pod", "kube-dns-sync", controller.EventReasonRecordAdded)).NotTo(
+					ContainElement(ContainSubstring("_kube-dns-sync.")))
+				Expect(eventMessages(events, "Node", "node1", controller.EventReasonAddressAdded)).To(ConsistOf(
+					`Added address 1.1.1.1 to A Record "externalip.test.com."`,
+				))
+				Expect(eventMessages(events, "Node", "node4", controller.EventReasonAddressAdded)).To(ConsistOf(
+					`Added address 4.4.4.4 to A Record "externalip.test.com."`,
+				))
+				Expect(eventMessages(events, "Node", "node3", controller.EventReasonAddressAdded)).To(BeEmpty())
+			},
+		}.Run(rrs)
+	})
+
+	It("should emit an event when a sync fails", func() {
+		pod := &api.ObjectReference{Kind: "Pod", Namespace: "kube-system", Name: "kube-dns-sync"}
+		Test{
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "missing.com.",
+				Client:       client,
+				SyncInterval: 100 * time.Millisecond,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				Events:       true,
+				EventObject:  pod,
+			},
+			Modify: func(c *controller.Controller) {
+				Expect(eventMessages(client.RecordedEvents(), "Pod", "kube-dns-sync", controller.EventReasonSyncFailed)).NotTo(BeEmpty())
+			},
+		}.Run(rrs)
+	})
+
+	It("should retry failed syncs with backoff", func() {
+		rrs.(*dnsproviderfake.ResourceRecordSetsFake).AddHook = failTimes(2)
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			Modify: func(c *controller.Controller) {
+				Expect(c.Healthy()).To(BeNil())
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval: time.Hour,
+				RetryBackoff: 50 * time.Millisecond,
+			},
+		}.Run(rrs)
+	})
+
+	It("should open the circuit breaker on persistent failures", func() {
+		rrs.(*dnsproviderfake.ResourceRecordSetsFake).AddHook = failAddressAdd("1.1.1.1")
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{},
+			Modify: func(c *controller.Controller) {
+				err := c.Ready()
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).To(ContainSubstring("circuit breaker open"))
+				Expect(c.Healthy()).To(BeNil())
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:      dns,
+				ZoneName:         "test.com.",
+				Client:           client,
+				AddressTypes:     []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval:     time.Hour,
+				RetryBackoff:     50 * time.Millisecond,
+				MaxBackoff:       100 * time.Millisecond,
+				FailureThreshold: 3,
+			},
+		}.Run(rrs)
+	})
+
+	It("should only probe with a sync every max backoff while the circuit breaker is open", func() {
+		rrs.(*dnsproviderfake.ResourceRecordSetsFake).AddHook = failAddressAdd("1.1.1.1")
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{},
+			Modify: func(c *controller.Controller) {
+				// Requested syncs are ignored as well.
+				node := k8sFixture[3]
+				node.Status.Addresses = append(node.Status.Addresses, api.NodeAddress{Type: api.NodeExternalIP, Address: "4.4.4.5"})
+				client.ModifyNode(node)
+				time.Sleep(500 * time.Millisecond)
+				err := c.Ready()
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).To(ContainSubstring("circuit breaker open after 2 consecutive failed syncs"))
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:      dns,
+				ZoneName:         "test.com.",
+				Client:           client,
+				AddressTypes:     []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval:     time.Hour,
+				Debounce:         50 * time.Millisecond,
+				RetryBackoff:     20 * time.Millisecond,
+				MaxBackoff:       time.Hour,
+				FailureThreshold: 2,
+			},
+		}.Run(rrs)
+	})
+
+	It("should not open the circuit breaker on fatal errors", func() {
+		Test{
+			Modify: func(c *controller.Controller) {
+				err := c.Ready()
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).NotTo(ContainSubstring("circuit breaker open"))
+				Expect(c.Healthy()).To(BeNil())
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:      dns,
+				ZoneName:         "missing.com.",
+				Client:           client,
+				AddressTypes:     []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval:     100 * time.Millisecond,
+				FailureThreshold: 1,
+			},
+		}.Run(rrs)
+	})
+
+	It("should coalesce a burst of Node changes into one sync", func() {
+		counter := &countingSource{}
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4", "10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval: time.Hour,
+				Debounce:     300 * time.Millisecond,
+				Sources:      []controller.Source{counter},
+			},
+			Modify: func(c *controller.Controller) {
+				Expect(counter.Count()).To(Equal(1))
+				for i := 1; i <= 5; i++ {
+					client.AddNode(api.Node{
+						ObjectMeta: api.ObjectMeta{Name: fmt.Sprintf("burst%d", i)},
+						Status: api.NodeStatus{
+							Addresses: []api.NodeAddress{
+								api.NodeAddress{Type: api.NodeExternalIP, Address: fmt.Sprintf("10.0.0.%d", i)},
+							},
+							Conditions: []api.NodeCondition{api.NodeCondition{
+								Type:   api.NodeReady,
+								Status: api.ConditionTrue,
+							}},
+						},
+					})
+				}
+				time.Sleep(time.Second)
+				Expect(counter.Count()).To(Equal(2))
+			},
+		}.Run(rrs)
+	})
+
+	It("should apply Node changes to cached Records", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4", "5.5.5.5"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:        dns,
+				ZoneName:           "test.com.",
+				Client:             client,
+				AddressTypes:       []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval:       200 * time.Millisecond,
+				DriftCheckInterval: time.Hour,
+			},
+			Modify: func(c *controller.Controller) {
+				client.AddNode(api.Node{
+					ObjectMeta: api.ObjectMeta{Name: "node5"},
+					Status: api.NodeStatus{
+						Addresses: []api.NodeAddress{
+							api.NodeAddress{Type: api.NodeExternalIP, Address: "5.5.5.5"},
+						},
+						Conditions: []api.NodeCondition{api.NodeCondition{
+							Type:   api.NodeReady,
+							Status: api.ConditionTrue,
+						}},
+					},
+				})
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should only detect out of band changes on drift checks", func() {
+		outOfBand := &dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 200, RRSDatas: []string{"2.2.2.2"}, RRSType: rrstype.A}
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				outOfBand,
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:        dns,
+				ZoneName:           "test.com.",
+				Client:             client,
+				AddressTypes:       []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval:       200 * time.Millisecond,
+				DriftCheckInterval: time.Hour,
+			},
+			Modify: func(c *controller.Controller) {
+				rrs.Remove(&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSType: rrstype.A})
+				rrs.Add(outOfBand)
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should correct out of band changes on drift checks", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:        dns,
+				ZoneName:           "test.com.",
+				Client:             client,
+				AddressTypes:       []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval:       200 * time.Millisecond,
+				DriftCheckInterval: 500 * time.Millisecond,
+			},
+			Modify: func(c *controller.Controller) {
+				rrs.Remove(&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSType: rrstype.A})
+				rrs.Add(&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 200, RRSDatas: []string{"2.2.2.2"}, RRSType: rrstype.A})
+				time.Sleep(1 * time.Second)
+			},
+		}.Run(rrs)
+	})
+
+	Describe("with node eligibility rules", func() {
+		eligibility := controller.NodeEligibility{
+			ExcludeUnschedulable: true,
+			ExcludeTaintEffects:  []api.TaintEffect{api.TaintEffectNoSchedule},
+			ExcludeConditions:    []api.NodeConditionType{api.NodeDiskPressure},
+		}
+
+		expectExcluded := func(modify func(node *api.Node)) {
+			Test{
+				Expected: []dnsprovider.ResourceRecordSet{
+					&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"4.4.4.4"}, RRSType: rrstype.A},
+					ownershipRecord("externalip.test.com.", 60),
+				},
+				ControllerOptions: controller.Options{
+					DNSProvider:     dns,
+					ZoneName:        "test.com.",
+					Client:          client,
+					AddressTypes:    []api.NodeAddressType{api.NodeExternalIP},
+					SyncInterval:    500 * time.Millisecond,
+					NodeEligibility: eligibility,
+				},
+				Modify: func(c *controller.Controller) {
+					node := k8sFixture[0]
+					modify(&node)
+					client.ModifyNode(node)
+					time.Sleep(500 * time.Millisecond)
+				},
+			}.Run(rrs)
+		}
+
+		It("should exclude unschedulable Nodes", func() {
+			expectExcluded(func(node *api.Node) {
+				node.Spec.Unschedulable = true
+			})
+		})
+
+		It("should exclude tainted Nodes", func() {
+			expectExcluded(func(node *api.Node) {
+				node.Annotations = map[string]string{
+					api.TaintsAnnotationKey: `[{"key":"dedicated","value":"maintenance","effect":"NoSchedule"}]`,
+				}
+			})
+		})
+
+		It("should exclude Nodes with excluded conditions", func() {
+			expectExcluded(func(node *api.Node) {
+				node.Status.Conditions = append([]api.NodeCondition{
+					{Type: api.NodeDiskPressure, Status: api.ConditionTrue},
+				}, node.Status.Conditions...)
+			})
+		})
+
+		It("should exclude annotated Nodes", func() {
+			expectExcluded(func(node *api.Node) {
+				node.Annotations = map[string]string{controller.AnnotationExclude: "true"}
+			})
+		})
+
+		expectKept := func(modify func(node *api.Node)) {
+			Test{
+				Expected: []dnsprovider.ResourceRecordSet{
+					&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+					ownershipRecord("externalip.test.com.", 60),
+				},
+				ControllerOptions: controller.Options{
+					DNSProvider:     dns,
+					ZoneName:        "test.com.",
+					Client:          client,
+					AddressTypes:    []api.NodeAddressType{api.NodeExternalIP},
+					SyncInterval:    500 * time.Millisecond,
+					NodeEligibility: eligibility,
+				},
+				Modify: func(c *controller.Controller) {
+					node := k8sFixture[0]
+					modify(&node)
+					client.ModifyNode(node)
+					time.Sleep(500 * time.Millisecond)
+				},
+			}.Run(rrs)
+		}
+
+		It("should keep Nodes with other taints", func() {
+			expectKept(func(node *api.Node) {
+				node.Annotations = map[string]string{
+					api.TaintsAnnotationKey: `[{"key":"dedicated","value":"batch","effect":"PreferNoSchedule"}]`,
+				}
+			})
+		})
+
+		It("should keep Nodes with invalid taints", func() {
+			expectKept(func(node *api.Node) {
+				node.Annotations = map[string]string{
+					api.TaintsAnnotationKey: `[{"key":"dedicated",`,
+				}
+			})
+		})
+	})
+
+	It("should override addresses with the Node annotation", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"9.9.9.9", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "internalip.test.com.", RRSTTL: 60, RRSDatas: []string{"10.0.0.1", "127.0.0.4"}, RRSType: rrstype.A},
+				ownershipRecord("internalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP, api.NodeInternalIP},
+				SyncInterval: time.Hour,
+			},
+			Modify: func(c *controller.Controller) {
+				node := k8sFixture[0]
+				node.Annotations = map[string]string{controller.AnnotationAddressOverride: "9.9.9.9,internalip=10.0.0.1"}
+				client.ModifyNode(node)
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should ignore an invalid address override", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval: time.Hour,
+			},
+			Modify: func(c *controller.Controller) {
+				node := k8sFixture[0]
+				node.Annotations = map[string]string{controller.AnnotationAddressOverride: "not-an-ip"}
+				client.ModifyNode(node)
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should publish extra names of the Node annotation", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "ci.test.com.", RRSTTL: 60, RRSDatas: []string{"4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("ci.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "ci.internal.test.com.", RRSTTL: 60, RRSDatas: []string{"127.0.0.4"}, RRSType: rrstype.A},
+				ownershipRecord("ci.internal.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval: time.Hour,
+			},
+			Modify: func(c *controller.Controller) {
+				node := k8sFixture[3]
+				node.Annotations = map[string]string{
+					controller.AnnotationExtraNames: "CI.test.com,internalip=ci.internal.test.com.,outside.example.com.",
+				}
+				client.ModifyNode(node)
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should not let extra names take over published names", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "ci.test.com.", RRSTTL: 60, RRSDatas: []string{"127.0.0.4"}, RRSType: rrstype.A},
+				ownershipRecord("ci.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval: time.Hour,
+			},
+			Modify: func(c *controller.Controller) {
+				node := k8sFixture[3]
+				node.Annotations = map[string]string{
+					controller.AnnotationExtraNames: "internalip=externalip.test.com.,internalip=ci.test.com.",
+				}
+				client.ModifyNode(node)
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should only sync Nodes annotated with the include annotation when required", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:     dns,
+				ZoneName:        "test.com.",
+				Client:          client,
+				AddressTypes:    []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval:    time.Hour,
+				NodeEligibility: controller.NodeEligibility{RequireInclude: true},
+			},
+			Modify: func(c *controller.Controller) {
+				node := k8sFixture[0]
+				node.Annotations = map[string]string{controller.AnnotationInclude: "false"}
+				client.ModifyNode(node)
+				node = k8sFixture[3]
+				node.Annotations = map[string]string{controller.AnnotationInclude: "true"}
+				client.ModifyNode(node)
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should sync a Record per value of the group label", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "pool-b.externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1"}, RRSType: rrstype.A},
+				ownershipRecord("pool-b.externalip.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "pool-a.externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("pool-a.externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				GroupByLabel: "pool",
+				SyncInterval: time.Hour,
+			},
+			Modify: func(c *controller.Controller) {
+				node := k8sFixture[0]
+				node.Labels = map[string]string{"pool": "Pool_B"}
+				client.ModifyNode(node)
+				node = k8sFixture[3]
+				node.Labels = map[string]string{"pool": "pool-a"}
+				client.ModifyNode(node)
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should sync a Record per failure domain zone and region", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "us-east-1a.externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1"}, RRSType: rrstype.A},
+				ownershipRecord("us-east-1a.externalip.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "us-east-1b.externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("us-east-1b.externalip.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "us-east-1.externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("us-east-1.externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:     dns,
+				ZoneName:        "test.com.",
+				Client:          client,
+				AddressTypes:    []api.NodeAddressType{api.NodeExternalIP},
+				TopologyRecords: true,
+				SyncInterval:    time.Hour,
+			},
+			Modify: func(c *controller.Controller) {
+				node := k8sFixture[0]
+				node.Labels = map[string]string{
+					unversioned.LabelZoneFailureDomain: "us-east-1a",
+					unversioned.LabelZoneRegion:        "us-east-1",
+				}
+				client.ModifyNode(node)
+				node = k8sFixture[3]
+				node.Labels = map[string]string{
+					unversioned.LabelZoneFailureDomain: "us-east-1b",
+					unversioned.LabelZoneRegion:        "us-east-1",
+				}
+				client.ModifyNode(node)
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should sync latency routed Records per region", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1"}, RRSType: rrstype.A,
+					RRSPolicy: routing.Policy{SetIdentifier: "us-east-1", Region: "us-east-1"}},
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"4.4.4.4"}, RRSType: rrstype.A,
+					RRSPolicy: routing.Policy{SetIdentifier: "eu-west-1", Region: "eu-west-1"}},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:    dns,
+				ZoneName:       "test.com.",
+				Client:         client,
+				AddressTypes:   []api.NodeAddressType{api.NodeExternalIP},
+				LatencyRouting: true,
+				SyncInterval:   time.Hour,
+			},
+			Modify: func(c *controller.Controller) {
+				node := k8sFixture[0]
+				node.Labels = map[string]string{unversioned.LabelZoneRegion: "us-east-1"}
+				client.ModifyNode(node)
+				node = k8sFixture[3]
+				node.Labels = map[string]string{unversioned.LabelZoneRegion: "eu-west-1"}
+				client.ModifyNode(node)
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should sync weighted Records bound to health checks per Node", func() {
+		node1Check, err := dns.AddHealthCheck("default", routing.HealthCheckTarget{Address: "1.1.1.1", Port: 30080})
+		Expect(err).To(BeNil())
+		_, err = dns.AddHealthCheck("default", routing.HealthCheckTarget{Address: "9.9.9.9", Port: 30080})
+		Expect(err).To(BeNil())
+		foreignCheck, err := dns.AddHealthCheck("other", routing.HealthCheckTarget{Address: "9.9.9.9", Port: 30080})
+		Expect(err).To(BeNil())
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1"}, RRSType: rrstype.A,
+					RRSPolicy: routing.Policy{SetIdentifier: "node1", Weighted: true, Weight: 1, HealthCheckID: node1Check}},
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"4.4.4.4"}, RRSType: rrstype.A,
+					RRSPolicy: routing.Policy{SetIdentifier: "node4", Weighted: true, Weight: 0, HealthCheckID: "hc-4"}},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:     dns,
+				ZoneName:        "test.com.",
+				Client:          client,
+				AddressTypes:    []api.NodeAddressType{api.NodeExternalIP},
+				WeightedRecords: true,
+				HealthCheckPort: 30080,
+				SyncInterval:    time.Hour,
+			},
+			Modify: func(c *controller.Controller) {
+				node := k8sFixture[3]
+				node.Annotations = map[string]string{controller.AnnotationWeight: "0"}
+				client.ModifyNode(node)
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+		Expect(dns.HealthCheckList).To(ConsistOf(
+			dnsproviderfake.HealthCheckFake{ID: node1Check, Owner: "default", Target: routing.HealthCheckTarget{Address: "1.1.1.1", Port: 30080}},
+			dnsproviderfake.HealthCheckFake{ID: "hc-4", Owner: "default", Target: routing.HealthCheckTarget{Address: "4.4.4.4", Port: 30080}},
+			dnsproviderfake.HealthCheckFake{ID: foreignCheck, Owner: "other", Target: routing.HealthCheckTarget{Address: "9.9.9.9", Port: 30080}},
+		))
+	})
+
+	It("should fail when combining latency routing and weighted records", func() {
+		_, err := controller.New(&controller.Options{
+			DNSProvider:     dns,
+			ZoneName:        "test.com.",
+			Client:          client,
+			AddressTypes:    []api.NodeAddressType{api.NodeExternalIP},
+			LatencyRouting:  true,
+			WeightedRecords: true,
+		})
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("should limit the addresses per Record to a stable subset of Nodes", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "test.com.", RRSTTL: 60, RRSDatas: []string{"4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:           dns,
+				ZoneName:              "test.com.",
+				Client:                client,
+				AddressTypes:          []api.NodeAddressType{api.NodeExternalIP},
+				ApexAddressType:       api.NodeExternalIP,
+				MaxAddressesPerRecord: 1,
+				SyncInterval:          time.Hour,
+			},
+		}.Run(rrs)
+	})
+
+	It("should sync again after Node changes during a sync", func() {
+		source := &blockingSource{}
+		newNode := func(name, address string) api.Node {
+			return api.Node{
+				ObjectMeta: api.ObjectMeta{Name: name},
+				Status: api.NodeStatus{
+					Addresses: []api.NodeAddress{
+						api.NodeAddress{Type: api.NodeExternalIP, Address: address},
+					},
+					Conditions: []api.NodeCondition{api.NodeCondition{
+						Type:   api.NodeReady,
+						Status: api.ConditionTrue,
+					}},
+				},
+			}
+		}
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4", "10.0.0.1", "10.0.0.2"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval: time.Hour,
+				Debounce:     100 * time.Millisecond,
+				Sources:      []controller.Source{source},
+			},
+			Modify: func(c *controller.Controller) {
+				Expect(source.Count()).To(Equal(1))
+				source.hold.Lock()
+				client.AddNode(newNode("during1", "10.0.0.1"))
+				// Wait for the sync to block, then change a Node while it is running.
+				time.Sleep(300 * time.Millisecond)
+				client.AddNode(newNode("during2", "10.0.0.2"))
+				time.Sleep(300 * time.Millisecond)
+				source.hold.Unlock()
+				time.Sleep(time.Second)
+				Expect(source.Count()).To(Equal(3))
+			},
+		}.Run(rrs)
+	})
+
+	It("should bind weighted AAAA Records to health checks of IPv6 addresses", func() {
+		node1Check, err := dns.AddHealthCheck("default", routing.HealthCheckTarget{Address: "1.1.1.1", Port: 30080})
+		Expect(err).To(BeNil())
+		node4Check, err := dns.AddHealthCheck("default", routing.HealthCheckTarget{Address: "4.4.4.4", Port: 30080})
+		Expect(err).To(BeNil())
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1"}, RRSType: rrstype.A,
+					RRSPolicy: routing.Policy{SetIdentifier: "node1", Weighted: true, Weight: 1, HealthCheckID: node1Check}},
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"4.4.4.4"}, RRSType: rrstype.A,
+					RRSPolicy: routing.Policy{SetIdentifier: "node4", Weighted: true, Weight: 1, HealthCheckID: node4Check}},
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"5.5.5.5"}, RRSType: rrstype.A,
+					RRSPolicy: routing.Policy{SetIdentifier: "node5", Weighted: true, Weight: 1, HealthCheckID: "hc-3"}},
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"2001:db8::5"}, RRSType: rrstype.AAAA,
+					RRSPolicy: routing.Policy{SetIdentifier: "node5", Weighted: true, Weight: 1, HealthCheckID: "hc-4"}},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:        dns,
+				ZoneName:           "test.com.",
+				Client:             client,
+				AddressTypes:       []api.NodeAddressType{api.NodeExternalIP},
+				WeightedRecords:    true,
+				HealthCheckPort:    30080,
+				SyncInterval:       time.Hour,
+				DriftCheckInterval: time.Hour,
+			},
+			Modify: func(c *controller.Controller) {
+				client.AddNode(dualStackNode)
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+		Expect(dns.HealthCheckList).To(ConsistOf(
+			dnsproviderfake.HealthCheckFake{ID: node1Check, Owner: "default", Target: routing.HealthCheckTarget{Address: "1.1.1.1", Port: 30080}},
+			dnsproviderfake.HealthCheckFake{ID: node4Check, Owner: "default", Target: routing.HealthCheckTarget{Address: "4.4.4.4", Port: 30080}},
+			dnsproviderfake.HealthCheckFake{ID: "hc-3", Owner: "default", Target: routing.HealthCheckTarget{Address: "5.5.5.5", Port: 30080}},
+			dnsproviderfake.HealthCheckFake{ID: "hc-4", Owner: "default", Target: routing.HealthCheckTarget{Address: "2001:db8::5", Port: 30080}},
+		))
+	})
+
+	It("should skip load balancers that only have hostnames", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "web.default.test.com.", RRSTTL: 60, RRSDatas: []string{"8.8.8.8"}, RRSType: rrstype.A},
+				ownershipRecord("web.default.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:   dns,
+				ZoneName:      "test.com.",
+				Client:        client,
+				TTL:           60,
+				AddressTypes:  []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval:  500 * time.Millisecond,
+				SyncServices:  true,
+				SyncIngresses: true,
+			},
+			Modify: func(c *controller.Controller) {
+				client.AddService(loadBalancerService)
+				client.AddService(hostnameService)
+				client.AddIngress(hostnameIngress)
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
+
+	It("should not sync before all watchers listed the Kubernetes API", func() {
+		web := &dnsproviderfake.ResourceRecordSetFake{RRSName: "web.default.test.com.", RRSTTL: 60, RRSDatas: []string{"8.8.8.8"}, RRSType: rrstype.A}
+		rrs.Add(web)
+		rrs.Add(ownershipRecord("web.default.test.com.", 60))
+		client.AddService(loadBalancerService)
+		client.HoldServiceList()
+		defer client.ReleaseServiceList()
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				web,
+				ownershipRecord("web.default.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval: 500 * time.Millisecond,
+				SyncServices: true,
+			},
+			Modify: func(c *controller.Controller) {
+				Expect(c.Ready()).NotTo(BeNil())
+			},
+		}.Run(rrs)
+	})
+
+	It("should skip names holding a CNAME Record", func() {
+		cname := &dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"lb.example.com."}, RRSType: rrstype.CNAME}
+		rrs.Add(cname)
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				cname,
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "internalip.test.com.", RRSTTL: 60, RRSDatas: []string{"127.0.0.1", "127.0.0.4"}, RRSType: rrstype.A},
+				ownershipRecord("internalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				TTL:          60,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP, api.NodeInternalIP},
+				SyncInterval: 500 * time.Millisecond,
+			},
+		}.Run(rrs)
+	})
+
+	It("should sync IPv4-mapped IPv6 addresses as IPv4 addresses", func() {
+		Test{
+			Expected: []dnsprovider.ResourceRecordSet{
+				&dnsproviderfake.ResourceRecordSetFake{RRSName: "externalip.test.com.", RRSTTL: 60, RRSDatas: []string{"1.1.1.1", "4.4.4.4", "5.5.5.5"}, RRSType: rrstype.A},
+				ownershipRecord("externalip.test.com.", 60),
+			},
+			ControllerOptions: controller.Options{
+				DNSProvider:  dns,
+				ZoneName:     "test.com.",
+				Client:       client,
+				AddressTypes: []api.NodeAddressType{api.NodeExternalIP},
+				SyncInterval: 500 * time.Millisecond,
+			},
+			Modify: func(c *controller.Controller) {
+				client.AddNode(api.Node{
+					ObjectMeta: api.ObjectMeta{Name: "mapped"},
+					Status: api.NodeStatus{
+						Addresses: []api.NodeAddress{
+							api.NodeAddress{Type: api.NodeExternalIP, Address: "::ffff:5.5.5.5"},
+						},
+						Conditions: []api.NodeCondition{api.NodeCondition{
+							Type:   api.NodeReady,
+							Status: api.ConditionTrue,
+						}},
+					},
+				})
+				time.Sleep(500 * time.Millisecond)
+			},
+		}.Run(rrs)
+	})
 })